@@ -0,0 +1,139 @@
+package pipenvinstall
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/paketo-buildpacks/packit/v2/servicebindings"
+)
+
+//go:generate faux --interface BindingResolver --output fakes/binding_resolver.go
+
+// BindingResolver defines the interface for resolving the service bindings of
+// a given type.
+type BindingResolver interface {
+	Resolve(typ, provider, platformDir string) ([]servicebindings.Binding, error)
+}
+
+// The service binding types that provide package index credentials, in order
+// of precedence.
+var indexBindingTypes = []string{"pipenv", "pip"}
+
+// resolveIndexBinding returns the package index credentials service binding,
+// if there is one. A binding of type pipenv takes precedence over one of type
+// pip.
+func resolveIndexBinding(resolver BindingResolver, platformDir string) (*servicebindings.Binding, error) {
+	for _, typ := range indexBindingTypes {
+		bindings, err := resolver.Resolve(typ, "", platformDir)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(bindings) > 1 {
+			return nil, fmt.Errorf("binding resolver found more than one binding of type '%s'", typ)
+		}
+
+		if len(bindings) == 1 {
+			return &bindings[0], nil
+		}
+	}
+
+	return nil, nil
+}
+
+// indexBindingEnv returns the environment variables that expose the entries of
+// the binding to pip: PIP_CONFIG_FILE for a pip.conf entry, NETRC for a .netrc
+// entry, and PIP_INDEX_URL for a PIP_INDEX_URL entry, with the username and
+// password entries added to its userinfo. The variables refer to the binding
+// files, so that no credentials are copied.
+func indexBindingEnv(binding servicebindings.Binding) ([]string, error) {
+	var env []string
+
+	if _, ok := binding.Entries["pip.conf"]; ok {
+		env = append(env, fmt.Sprintf("PIP_CONFIG_FILE=%s", filepath.Join(binding.Path, "pip.conf")))
+	}
+
+	if _, ok := binding.Entries[".netrc"]; ok {
+		env = append(env, fmt.Sprintf("NETRC=%s", filepath.Join(binding.Path, ".netrc")))
+	}
+
+	indexURL, err := readBindingEntry(binding, "PIP_INDEX_URL")
+	if err != nil {
+		return nil, err
+	}
+
+	username, err := readBindingEntry(binding, "username")
+	if err != nil {
+		return nil, err
+	}
+
+	password, err := readBindingEntry(binding, "password")
+	if err != nil {
+		return nil, err
+	}
+
+	if indexURL == "" {
+		if username != "" || password != "" {
+			return nil, fmt.Errorf("binding '%s' of type '%s': username and password entries require a PIP_INDEX_URL entry", binding.Name, binding.Type)
+		}
+
+		return env, nil
+	}
+
+	if username != "" {
+		uri, err := url.Parse(indexURL)
+		if err != nil {
+			return nil, fmt.Errorf("binding '%s' of type '%s': failed to parse PIP_INDEX_URL: %w", binding.Name, binding.Type, err)
+		}
+
+		uri.User = url.User(username)
+		if password != "" {
+			uri.User = url.UserPassword(username, password)
+		}
+		indexURL = uri.String()
+	}
+
+	return append(env, fmt.Sprintf("PIP_INDEX_URL=%s", indexURL)), nil
+}
+
+// readBindingEntry returns the trimmed content of the named binding entry, or
+// an empty string when the binding has no such entry.
+func readBindingEntry(binding servicebindings.Binding, name string) (string, error) {
+	entry, ok := binding.Entries[name]
+	if !ok {
+		return "", nil
+	}
+
+	value, err := entry.ReadString()
+	if err != nil {
+		return "", fmt.Errorf("failed to read entry '%s' of binding '%s': %w", name, binding.Name, err)
+	}
+
+	return strings.TrimSpace(value), nil
+}
+
+// withEnv runs f with the environment variables vars set, and restores their
+// previous values once f returns. The error of f is redacted while the
+// variables are still set.
+func withEnv(vars []string, f func() error) error {
+	for _, variable := range vars {
+		name, value, _ := strings.Cut(variable, "=")
+
+		previous, ok := os.LookupEnv(name)
+		if ok {
+			defer os.Setenv(name, previous)
+		} else {
+			defer os.Unsetenv(name)
+		}
+
+		err := os.Setenv(name, value)
+		if err != nil {
+			return err
+		}
+	}
+
+	return RedactError(f())
+}
@@ -1,8 +1,13 @@
 package pipenvinstall
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/paketo-buildpacks/packit/v2"
@@ -17,42 +22,79 @@ import (
 //go:generate faux --interface SitePackagesProcess --output fakes/site_packages_process.go
 //go:generate faux --interface VenvDirLocator --output fakes/venv_dir_locator.go
 //go:generate faux --interface SBOMGenerator --output fakes/sbom_generator.go
+//go:generate faux --interface LockHashParser --output fakes/lock_hash_parser.go
+//go:generate faux --interface PythonVersionProcess --output fakes/python_version_process.go
+//go:generate faux --interface ScriptsParser --output fakes/scripts_parser.go
+//go:generate faux --interface PostInstallProcess --output fakes/post_install_process.go
 
-// SitePackagesProcess defines the interface for determining the site-packages path.
+// SitePackagesProcess defines the interface for determining the site-packages
+// path of the virtual env at venvDir.
 type SitePackagesProcess interface {
-	Execute(layerPath string) (sitePackagesPath string, err error)
+	Execute(venvDir string) (sitePackagesPath string, err error)
 }
 
 // InstallProcess defines the interface for installing the pipenv dependencies.
+// When dev is true the dev-packages are installed as well.
 type InstallProcess interface {
-	Execute(workingDir string, targetLayer, cacheLayer packit.Layer) error
+	Execute(workingDir string, targetLayer, cacheLayer packit.Layer, dev bool) error
 }
 
 // VenvDirLocator defines the interface for locating the virtual environment
-// directory under a given path
+// directory of the workingDir project under a given path
 type VenvDirLocator interface {
-	LocateVenvDir(path string) (venvDir string, err error)
+	LocateVenvDir(path, workingDir string) (venvDir string, err error)
 }
 
 type SBOMGenerator interface {
 	Generate(dir string) (sbom.SBOM, error)
 }
 
+// LockHashParser defines the interface for reading the Pipfile content hash
+// recorded in Pipfile.lock, and for checking it against the Pipfile.
+type LockHashParser interface {
+	ParseHash(path string) (hash string, err error)
+	CheckSync(path string) (inSync bool, err error)
+}
+
+// PythonVersionProcess defines the interface for determining the version of
+// the python interpreter used to install the packages.
+type PythonVersionProcess interface {
+	Execute() (version string, err error)
+}
+
+// ScriptsParser defines the interface for reading the [scripts] declared in
+// Pipfile.
+type ScriptsParser interface {
+	ParseScripts(path string) (scripts map[string]string, err error)
+}
+
+// PostInstallProcess defines the interface for running a Pipfile script with
+// the virtual environment at venvDir in the targetLayer.
+type PostInstallProcess interface {
+	Execute(workingDir, script string, targetLayer packit.Layer, venvDir string) error
+}
+
 // Build will return a packit.BuildFunc that will be invoked during the build
 // phase of the buildpack lifecycle.
 //
 // Build will install the pipenv dependencies by using the Pipfile to a
-// packages layer. It also makes use of a cache layer to reuse the pipenv
-// cache.
+// packages layer, or to a build-only dev-packages layer along with the
+// dev-packages when a downstream buildpack requests them. It also makes use of
+// a cache layer to reuse the pipenv cache.
 func Build(
 	installProcess InstallProcess,
 	siteProcess SitePackagesProcess,
 	venvDirLocator VenvDirLocator,
 	sbomGenerator SBOMGenerator,
+	lockHashParser LockHashParser,
+	pythonVersionProcess PythonVersionProcess,
+	scriptsParser ScriptsParser,
+	postInstallProcess PostInstallProcess,
+	bindingResolver BindingResolver,
 	clock chronos.Clock,
 	logger scribe.Emitter,
 ) packit.BuildFunc {
-	return func(context packit.BuildContext) (packit.BuildResult, error) {
+	build := func(context packit.BuildContext) (packit.BuildResult, error) {
 		logger.Title("%s %s", context.BuildpackInfo.Name, context.BuildpackInfo.Version)
 
 		packagesLayer, err := context.Layers.Get(PackagesLayerName)
@@ -65,58 +107,276 @@ func Build(
 			return packit.BuildResult{}, err
 		}
 
+		pythonVersion, err := pythonVersionProcess.Execute()
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		// A virtualenv and the compiled wheels in the pipenv cache are tied to
+		// the python minor version they were built with.
+		packagesLayer, err = resetOnPythonChange(packagesLayer, pythonVersion, logger)
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		cacheLayer, err = resetOnPythonChange(cacheLayer, pythonVersion, logger)
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
 		planner := draft.NewPlanner()
 		packagesLayer.Launch, packagesLayer.Build = planner.MergeLayerTypes(SitePackages, context.Plan.Entries)
 		packagesLayer.Cache = packagesLayer.Launch || packagesLayer.Build
 		cacheLayer.Cache = true
+		cacheLayer.Metadata = map[string]interface{}{
+			CPythonVersionName: pythonVersion,
+		}
 
-		logger.Process("Executing build process")
-		duration, err := clock.Measure(func() error {
-			return installProcess.Execute(context.WorkingDir, packagesLayer, cacheLayer)
-		})
-		if err != nil {
+		lockSHA, err := lockHashParser.ParseHash(context.WorkingDir)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
 			return packit.BuildResult{}, err
 		}
 
-		logger.Action("Completed in %s", duration.Round(time.Millisecond))
-		logger.Break()
+		// A stale lock is relocked, or fails the install with --deploy, so a layer
+		// installed from it is never reused.
+		reusable := false
+		if lockSHA != "" {
+			reusable, err = lockHashParser.CheckSync(context.WorkingDir)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+		}
 
-		venvDir, err := venvDirLocator.LocateVenvDir(packagesLayer.Path)
-		if err != nil {
+		lockChecksum, err := fs.NewChecksumCalculator().Sum(filepath.Join(context.WorkingDir, "Pipfile.lock"))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
 			return packit.BuildResult{}, err
 		}
 
-		sitePackagesPath, err := siteProcess.Execute(packagesLayer.Path)
+		metadata := map[string]interface{}{
+			LockfileShaName:      lockSHA,
+			LockfileChecksumName: lockChecksum,
+			CPythonVersionName:   pythonVersion,
+			StackName:            context.Stack,
+			ArchName:             runtime.GOARCH,
+			CategoriesName:       normalizeCategories(os.Getenv("BP_PIPENV_CATEGORIES")),
+		}
+
+		binding, err := resolveIndexBinding(bindingResolver, context.Platform.Path)
 		if err != nil {
 			return packit.BuildResult{}, err
 		}
 
-		logger.GeneratingSBOM(packagesLayer.Path)
+		// The credentials are only set in the environment while pipenv runs, so
+		// that they do not end up in any layer.
+		var bindingEnv []string
+		if binding != nil {
+			bindingEnv, err = indexBindingEnv(*binding)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+		}
 
-		var sbomContent sbom.SBOM
-		duration, err = clock.Measure(func() error {
-			sbomContent, err = sbomGenerator.Generate(context.WorkingDir)
-			return err
-		})
+		bundle, err := newCABundle(bindingResolver, context.Platform.Path, context.WorkingDir)
 		if err != nil {
 			return packit.BuildResult{}, err
 		}
-		logger.Action("Completed in %s", duration.Round(time.Millisecond))
-		logger.Break()
 
-		logger.FormattingSBOM(context.BuildpackInfo.SBOMFormats...)
+		installEnv := bindingEnv
+		if bundle != nil {
+			defer bundle.Remove()
+			installEnv = append(installEnv, bundle.Env()...)
+		}
+
+		install := func(layer packit.Layer, dev bool) (packit.Layer, error) {
+			if reusable && layerMetadataMatches(layer.Metadata, metadata) {
+				logger.Process("Reusing cached layer %s", layer.Path)
+				logger.Break()
+				return layer, nil
+			}
 
-		packagesLayer.SBOM, err = sbomContent.InFormats(context.BuildpackInfo.SBOMFormats...)
-		if err != nil {
-			return packit.BuildResult{}, err
+			if dev {
+				logger.Process("Executing build process for dev-packages")
+			} else {
+				logger.Process("Executing build process")
+			}
+			if binding != nil {
+				logger.Subprocess("Using package index credentials from binding '%s'", binding.Name)
+			}
+
+			if bundle != nil {
+				logger.Subprocess("Using CA certificates from %s", strings.Join(bundle.Sources, ", "))
+			}
+
+			duration, err := clock.Measure(func() error {
+				return withEnv(installEnv, func() error {
+					return installProcess.Execute(context.WorkingDir, layer, cacheLayer, dev)
+				})
+			})
+			if err != nil {
+				return packit.Layer{}, err
+			}
+
+			logger.Action("Completed in %s", duration.Round(time.Millisecond))
+			logger.Break()
+
+			layer.Metadata = metadata
+			return layer, nil
 		}
 
-		packagesLayer.SharedEnv.Prepend("PATH", filepath.Join(venvDir, "bin"), ":")
-		packagesLayer.SharedEnv.Prepend("PYTHONPATH", sitePackagesPath, string(os.PathListSeparator))
+		devRequested := devPackagesRequested(context.Plan.Entries)
+
+		var (
+			layers        []packit.Layer
+			venvDir       string
+			scriptLayer   packit.Layer
+			scriptVenvDir string
+		)
 
-		logger.EnvironmentVariables(packagesLayer)
+		// The dev-packages layer holds the packages as well, so when the
+		// packages are not needed at launch it alone is installed.
+		if packagesLayer.Launch || !devRequested {
+			packagesLayer, err = install(packagesLayer, false)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+
+			venvDir, err = venvDirLocator.LocateVenvDir(packagesLayer.Path, context.WorkingDir)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+
+			pythonLink, repaired, err := CheckVenvInterpreter(venvDir, os.Getenv("PATH"))
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+
+			if repaired {
+				logger.Process("Repaired virtual env interpreter link to %s", pythonLink)
+				logger.Break()
+			}
+
+			// Record the interpreter without changing the metadata the
+			// dev-packages layer is compared against.
+			packagesMetadata := map[string]interface{}{PythonLinkName: pythonLink}
+			for key, value := range packagesLayer.Metadata {
+				if key != PythonLinkName {
+					packagesMetadata[key] = value
+				}
+			}
+			packagesLayer.Metadata = packagesMetadata
+
+			if packagesLayer.Launch {
+				packagesLayer.ExecD = []string{filepath.Join(context.CNBPath, "bin", VenvInterpreterCheck)}
+			}
+
+			sitePackagesPath, err := siteProcess.Execute(venvDir)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+
+			logger.GeneratingSBOM(packagesLayer.Path)
+
+			var sbomContent sbom.SBOM
+			duration, err := clock.Measure(func() error {
+				sbomContent, err = sbomGenerator.Generate(context.WorkingDir)
+				return err
+			})
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+			logger.Action("Completed in %s", duration.Round(time.Millisecond))
+			logger.Break()
+
+			logger.FormattingSBOM(context.BuildpackInfo.SBOMFormats...)
+
+			packagesLayer.SBOM, err = sbomContent.InFormats(context.BuildpackInfo.SBOMFormats...)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+
+			// The layer env is applied in lexical order, so the packages layer
+			// would shadow the virtual env of the dev-packages layer at build
+			// time. That virtual env holds the packages as well, so it alone is
+			// used for build.
+			packagesEnv := packagesLayer.SharedEnv
+			if devRequested {
+				packagesEnv = packagesLayer.LaunchEnv
+			}
+
+			packagesEnv.Prepend("PATH", filepath.Join(venvDir, "bin"), ":")
+			packagesEnv.Prepend("PYTHONPATH", sitePackagesPath, string(os.PathListSeparator))
+			setVenvEnv(packagesEnv, packagesLayer.Path, venvDir)
+
+			logger.EnvironmentVariables(packagesLayer)
+
+			layers = append(layers, packagesLayer)
+			scriptLayer, scriptVenvDir = packagesLayer, venvDir
+		}
+
+		if devRequested {
+			devPackagesLayer, err := context.Layers.Get(DevPackagesLayerName)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+
+			devPackagesLayer, err = resetOnPythonChange(devPackagesLayer, pythonVersion, logger)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+
+			// dev-packages are never exported into the launch image.
+			devPackagesLayer.Build = true
+			devPackagesLayer.Cache = true
+
+			devPackagesLayer, err = install(devPackagesLayer, true)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+
+			devVenvDir, err := venvDirLocator.LocateVenvDir(devPackagesLayer.Path, context.WorkingDir)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+
+			devSitePackagesPath, err := siteProcess.Execute(devVenvDir)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+
+			devPackagesLayer.BuildEnv.Prepend("PATH", filepath.Join(devVenvDir, "bin"), ":")
+			devPackagesLayer.BuildEnv.Prepend("PYTHONPATH", devSitePackagesPath, string(os.PathListSeparator))
+			setVenvEnv(devPackagesLayer.BuildEnv, devPackagesLayer.Path, devVenvDir)
+
+			logger.EnvironmentVariables(devPackagesLayer)
+
+			layers = append(layers, devPackagesLayer)
+			if scriptVenvDir == "" {
+				scriptLayer, scriptVenvDir = devPackagesLayer, devVenvDir
+			}
+		}
+
+		if script, ok := os.LookupEnv("BP_PIPENV_POST_INSTALL"); ok && script != "" {
+			scripts, err := scriptsParser.ParseScripts(context.WorkingDir)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+
+			if _, ok := scripts[script]; !ok {
+				return packit.BuildResult{}, fmt.Errorf("BP_PIPENV_POST_INSTALL: script '%s' is not declared in 'Pipfile' [scripts]", script)
+			}
+
+			logger.Process("Executing post-install script '%s'", script)
+			duration, err := clock.Measure(func() error {
+				return postInstallProcess.Execute(context.WorkingDir, script, scriptLayer, scriptVenvDir)
+			})
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+
+			logger.Action("Completed in %s", duration.Round(time.Millisecond))
+			logger.Break()
+		}
 
-		layers := []packit.Layer{packagesLayer}
 		if _, err := os.Stat(cacheLayer.Path); err == nil {
 			if !fs.IsEmptyDir(cacheLayer.Path) {
 				layers = append(layers, cacheLayer)
@@ -127,6 +387,117 @@ func Build(
 			Layers: layers,
 		}
 
+		scriptsAsProcesses, err := parseBoolEnv("BP_PIPENV_SCRIPTS_AS_PROCESSES")
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		if scriptsAsProcesses {
+			scripts, err := scriptsParser.ParseScripts(context.WorkingDir)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+
+			// The venv is only available at launch when the packages layer is.
+			binDir := ""
+			if packagesLayer.Launch {
+				binDir = filepath.Join(venvDir, "bin")
+			}
+
+			result.Launch.Processes, err = scriptProcesses(scripts, binDir, os.Getenv("BP_PIPENV_DEFAULT_PROCESS"))
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+
+			logger.LaunchProcesses(result.Launch.Processes)
+		}
+
 		return result, nil
 	}
+
+	return func(context packit.BuildContext) (packit.BuildResult, error) {
+		result, err := build(context)
+		if err != nil {
+			return packit.BuildResult{}, RedactError(err)
+		}
+
+		result.Layers = redactLayers(result.Layers)
+		return result, nil
+	}
+}
+
+// setVenvEnv sets the variables of an activated pipenv virtual env, so that
+// the venv is recognized as active and `pipenv run` finds it in the layer.
+func setVenvEnv(env packit.Environment, layerPath, venvDir string) {
+	env.Override("VIRTUAL_ENV", venvDir)
+	env.Override("PIPENV_ACTIVE", "1")
+	env.Override("WORKON_HOME", layerPath)
+	env.Override("PIPENV_CUSTOM_VENV_NAME", filepath.Base(venvDir))
+}
+
+// normalizeCategories returns the sorted, unique categories of the given
+// list, separated by a space, so that listing the same categories differently
+// does not invalidate the layers.
+func normalizeCategories(value string) string {
+	seen := map[string]bool{}
+
+	var categories []string
+	for _, category := range splitCategories(value) {
+		if !seen[category] {
+			seen[category] = true
+			categories = append(categories, category)
+		}
+	}
+	sort.Strings(categories)
+
+	return strings.Join(categories, " ")
+}
+
+// devPackagesRequested returns the OR result of the dev key for all of the
+// site-packages buildpack plan entries, merged in the same fashion as
+// draft.Planner.MergeLayerTypes merges the build and launch keys.
+func devPackagesRequested(entries []packit.BuildpackPlanEntry) bool {
+	for _, e := range entries {
+		if e.Name == SitePackages && e.Metadata["dev"] == true {
+			return true
+		}
+	}
+
+	return false
+}
+
+// layerMetadataMatches reports whether the actual layer metadata holds all of
+// the expected values, i.e. whether the layer was installed from the same
+// Pipfile.lock content, python version, stack, architecture and categories.
+func layerMetadataMatches(actual, expected map[string]interface{}) bool {
+	for key, value := range expected {
+		if actual[key] != value {
+			return false
+		}
+	}
+
+	return true
+}
+
+// resetOnPythonChange resets the given layer when it was built with a python
+// minor version other than the given one.
+func resetOnPythonChange(layer packit.Layer, pythonVersion string, logger scribe.Emitter) (packit.Layer, error) {
+	previousVersion, ok := layer.Metadata[CPythonVersionName].(string)
+	if !ok || minorVersion(previousVersion) == minorVersion(pythonVersion) {
+		return layer, nil
+	}
+
+	logger.Process("Python version changed from %s to %s, resetting %s layer", previousVersion, pythonVersion, layer.Name)
+	logger.Break()
+
+	return layer.Reset()
+}
+
+func minorVersion(version string) string {
+	parts := strings.SplitN(version, ".", 3)
+	if len(parts) < 2 {
+		return version
+	}
+
+	return strings.Join(parts[:2], ".")
 }
@@ -0,0 +1,171 @@
+package pipenvinstall
+
+import (
+	"bytes"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// systemCABundles are the locations of the system CA bundle on the supported
+// stacks, used when $SSL_CERT_FILE is not set.
+var systemCABundles = []string{
+	"/etc/ssl/certs/ca-certificates.crt",
+	"/etc/pki/tls/certs/ca-bundle.crt",
+	"/etc/ssl/cert.pem",
+}
+
+// caBundle is a temporary CA bundle of the system CA certificates and the
+// custom ones from the Sources.
+type caBundle struct {
+	Path    string
+	Sources []string
+}
+
+// Env returns the environment variables that make pip and requests verify
+// against the bundle.
+func (b caBundle) Env() []string {
+	return []string{
+		fmt.Sprintf("REQUESTS_CA_BUNDLE=%s", b.Path),
+		fmt.Sprintf("PIP_CERT=%s", b.Path),
+	}
+}
+
+// Remove removes the bundle file.
+func (b caBundle) Remove() error {
+	return os.Remove(b.Path)
+}
+
+// newCABundle writes the certificates of the ca-certificates service bindings
+// and of the $BP_PIPENV_CA_BUNDLE file, appended to the system CA bundle, into
+// a temporary file. A relative $BP_PIPENV_CA_BUNDLE is taken from the
+// workingDir. It returns nil when there are no custom certificates.
+func newCABundle(resolver BindingResolver, platformDir, workingDir string) (*caBundle, error) {
+	bindings, err := resolver.Resolve(CACertificatesBindingType, "", platformDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		sources []string
+		custom  [][]byte
+	)
+
+	for _, binding := range bindings {
+		var names []string
+		for name := range binding.Entries {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			content, err := binding.Entries[name].ReadBytes()
+			if err != nil {
+				return nil, fmt.Errorf("failed to read entry '%s' of binding '%s': %w", name, binding.Name, err)
+			}
+
+			err = checkCertificates(content, name)
+			if err != nil {
+				return nil, fmt.Errorf("binding '%s' of type '%s': %w", binding.Name, binding.Type, err)
+			}
+			custom = append(custom, content)
+		}
+
+		sources = append(sources, fmt.Sprintf("binding '%s'", binding.Name))
+	}
+
+	if path, ok := os.LookupEnv("BP_PIPENV_CA_BUNDLE"); ok && path != "" {
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(workingDir, path)
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("BP_PIPENV_CA_BUNDLE: failed to read CA certificates: %w", err)
+		}
+
+		err = checkCertificates(content, path)
+		if err != nil {
+			return nil, fmt.Errorf("BP_PIPENV_CA_BUNDLE: %w", err)
+		}
+		custom = append(custom, content)
+
+		sources = append(sources, path)
+	}
+
+	if len(custom) == 0 {
+		return nil, nil
+	}
+
+	system, err := readSystemCABundle()
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.CreateTemp("", "pipenv-ca-bundle-*.pem")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CA bundle: %w", err)
+	}
+	defer file.Close()
+
+	for _, content := range append([][]byte{system}, custom...) {
+		if len(content) > 0 && !bytes.HasSuffix(content, []byte("\n")) {
+			content = append(content, '\n')
+		}
+
+		_, err = file.Write(content)
+		if err != nil {
+			os.Remove(file.Name())
+			return nil, fmt.Errorf("failed to write CA bundle: %w", err)
+		}
+	}
+
+	return &caBundle{
+		Path:    file.Name(),
+		Sources: sources,
+	}, nil
+}
+
+// checkCertificates checks that the PEM content of the named file contains at
+// least one certificate.
+func checkCertificates(content []byte, name string) error {
+	rest := content
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			return fmt.Errorf("%s does not contain a PEM encoded certificate", name)
+		}
+
+		if block.Type == "CERTIFICATE" {
+			return nil
+		}
+	}
+}
+
+// readSystemCABundle reads the CA bundle at $SSL_CERT_FILE, or else at the
+// first of the systemCABundles that exists. It is empty when there is none.
+func readSystemCABundle() ([]byte, error) {
+	paths := systemCABundles
+	if path, ok := os.LookupEnv("SSL_CERT_FILE"); ok && path != "" {
+		paths = []string{path}
+	}
+
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+
+			return nil, fmt.Errorf("failed to read system CA bundle: %w", err)
+		}
+
+		return content, nil
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	pipenvinstall "github.com/paketo-buildpacks/pipenv-install"
+)
+
+// venv-interpreter-check runs from the exec.d directory of the packages layer
+// at launch. It checks that the virtual env interpreter link still resolves,
+// repairing it when a matching python is on the PATH, and fails the launch
+// otherwise.
+func main() {
+	err := run()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", pipenvinstall.VenvInterpreterCheck, err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	// The executable is <layer>/exec.d/<n>-venv-interpreter-check.
+	layerPath := filepath.Dir(filepath.Dir(executable))
+
+	venvDir, err := findVenvDir(layerPath)
+	if err != nil {
+		return err
+	}
+
+	target, repaired, err := pipenvinstall.CheckVenvInterpreter(venvDir, os.Getenv("PATH"))
+	if err != nil {
+		return err
+	}
+
+	if repaired {
+		fmt.Fprintf(os.Stderr, "%s: repaired virtual env interpreter link to %s\n", pipenvinstall.VenvInterpreterCheck, target)
+	}
+
+	return nil
+}
+
+// findVenvDir returns the only virtual env in the packages layer.
+func findVenvDir(layerPath string) (string, error) {
+	matches, err := filepath.Glob(filepath.Join(layerPath, "*", "pyvenv.cfg"))
+	if err != nil {
+		return "", err
+	}
+
+	if len(matches) != 1 {
+		return "", fmt.Errorf("found %d virtual envs in %s", len(matches), layerPath)
+	}
+
+	return filepath.Dir(matches[0]), nil
+}
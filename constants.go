@@ -16,5 +16,54 @@ const Pipenv = "pipenv"
 // installed to.
 const PackagesLayerName = "packages"
 
+// The layer name for dev-packages layer. This build-only layer is where
+// dependencies are installed to, along with the dev-packages, when a
+// downstream buildpack requests them.
+const DevPackagesLayerName = "dev-packages"
+
 // The layer name for cache layer. This layer holds the pipenv cache.
 const CacheLayerName = "cache"
+
+// The packages layer metadata key holding the _meta.hash.sha256 value of the
+// Pipfile.lock the layer was built from.
+const LockfileShaName = "lockfile-sha"
+
+// The packages layer metadata key holding the checksum of the whole
+// Pipfile.lock the layer was built from. Unlike its _meta.hash, it changes
+// when the pins are updated for an unchanged Pipfile.
+const LockfileChecksumName = "lockfile-checksum"
+
+// The layer metadata key holding the CPython version a layer was built with.
+const CPythonVersionName = "cpython-version"
+
+// The layer metadata key holding the stack id a layer was built on.
+const StackName = "stack"
+
+// The layer metadata key holding the architecture a layer was built on.
+const ArchName = "arch"
+
+// The layer metadata key holding the sorted, space separated
+// $BP_PIPENV_CATEGORIES a layer was installed with.
+const CategoriesName = "categories"
+
+// The packages layer metadata key holding the interpreter the bin/python link
+// of the virtual env pointed to at build time.
+const PythonLinkName = "python-link"
+
+// The name of the exec.d executable that checks, and repairs, the virtual env
+// interpreter link at launch.
+const VenvInterpreterCheck = "venv-interpreter-check"
+
+// The type of the service bindings whose entries are PEM encoded CA
+// certificates to trust when installing the packages.
+const CACertificatesBindingType = "ca-certificates"
+
+// The lock modes accepted by $BP_PIPENV_LOCK_MODE. LockModeStrict installs
+// with --deploy and fails when Pipfile.lock is out of date, LockModeRelock
+// regenerates Pipfile.lock before installing and LockModeIgnore installs from
+// Pipfile.lock with --ignore-pipfile.
+const (
+	LockModeStrict = "strict"
+	LockModeRelock = "relock"
+	LockModeIgnore = "ignore"
+)
@@ -2,11 +2,16 @@ package pipenvinstall
 
 import (
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
 
 	"github.com/paketo-buildpacks/packit/v2"
 	"github.com/paketo-buildpacks/packit/v2/fs"
+	"github.com/paketo-buildpacks/packit/v2/scribe"
 )
 
 // BuildPlanMetadata is the buildpack-specific data included in build plan
@@ -25,14 +30,35 @@ type BuildPlanMetadata struct {
 // Parser will parse python version out of Pipfile.lock.
 type Parser interface {
 	ParseVersion(path string) (version string, err error)
+	ParseFullVersion(path string) (version string, err error)
+}
+
+//go:generate faux --interface CategoriesParser --output fakes/categories_parser.go
+
+// CategoriesParser will parse the package categories declared in Pipfile.
+type CategoriesParser interface {
+	ParseCategories(path string) (categories []string, err error)
+}
+
+//go:generate faux --interface LockSyncChecker --output fakes/lock_sync_checker.go
+
+// LockSyncChecker will check whether Pipfile.lock is in sync with Pipfile.
+type LockSyncChecker interface {
+	CheckSync(path string) (inSync bool, err error)
 }
 
 // Detect will return a packit.DetectFunc that will be invoked during the
 // detect phase of the buildpack lifecycle.
 //
 // Detection will contribute a Build Plan that provides site-packages,
-// and requires cpython and pipenv at build.
-func Detect(pipfileParser, pipfileLockParser Parser) packit.DetectFunc {
+// and requires cpython and pipenv at build. The cpython version is taken from
+// python_full_version, or python_version, of Pipfile.lock or Pipfile. An error
+// is returned when $BP_PIPENV_CATEGORIES names a category that is not declared
+// in the Pipfile, when Pipfile.lock is out of sync with Pipfile and when the
+// python versions they require conflict, unless the $BP_PIPENV_LOCK_CHECK or
+// $BP_PIPENV_PYTHON_VERSION_CHECK respectively is "warn". A stale Pipfile.lock
+// is also tolerated when $BP_PIPENV_LOCK_MODE is not "strict".
+func Detect(pipfileParser, pipfileLockParser Parser, categoriesParser CategoriesParser, lockSyncChecker LockSyncChecker, logger scribe.Emitter) packit.DetectFunc {
 	return func(context packit.DetectContext) (packit.DetectResult, error) {
 		exists, err := fs.Exists(filepath.Join(context.WorkingDir, "Pipfile"))
 		if err != nil {
@@ -44,6 +70,38 @@ func Detect(pipfileParser, pipfileLockParser Parser) packit.DetectFunc {
 			return packit.DetectResult{}, packit.Fail.WithMessage("no 'Pipfile' found")
 		}
 
+		lockCheck := os.Getenv("BP_PIPENV_LOCK_CHECK")
+		switch lockCheck {
+		case "", "fail", "warn":
+		default:
+			return packit.DetectResult{}, fmt.Errorf("invalid BP_PIPENV_LOCK_CHECK value %q: must be one of 'fail' or 'warn'", lockCheck)
+		}
+
+		versionCheck := os.Getenv("BP_PIPENV_PYTHON_VERSION_CHECK")
+		switch versionCheck {
+		case "", "fail", "warn":
+		default:
+			return packit.DetectResult{}, fmt.Errorf("invalid BP_PIPENV_PYTHON_VERSION_CHECK value %q: must be one of 'fail' or 'warn'", versionCheck)
+		}
+
+		if value, ok := os.LookupEnv("BP_PIPENV_CATEGORIES"); ok {
+			declared, err := categoriesParser.ParseCategories(context.WorkingDir)
+			if err != nil {
+				return packit.DetectResult{}, err
+			}
+
+			declaredSet := map[string]bool{}
+			for _, category := range declared {
+				declaredSet[category] = true
+			}
+
+			for _, category := range splitCategories(value) {
+				if !declaredSet[category] {
+					return packit.DetectResult{}, fmt.Errorf("BP_PIPENV_CATEGORIES: category '%s' is not declared in 'Pipfile' (declared categories: %s)", category, strings.Join(declared, ", "))
+				}
+			}
+		}
+
 		cpythonRequirement := packit.BuildPlanRequirement{
 			Name: CPython,
 			Metadata: BuildPlanMetadata{
@@ -57,11 +115,41 @@ func Detect(pipfileParser, pipfileLockParser Parser) packit.DetectFunc {
 		}
 
 		if lockFileExists {
-			cpythonVersion, err := pipfileLockParser.ParseVersion(context.WorkingDir)
+			lockMode, err := parseLockMode()
+			if err != nil {
+				return packit.DetectResult{}, err
+			}
+
+			inSync, err := lockSyncChecker.CheckSync(context.WorkingDir)
+			if err != nil {
+				return packit.DetectResult{}, err
+			}
+
+			if !inSync {
+				// The relock and ignore lock modes tolerate a stale lock at build time.
+				if lockCheck == "warn" || lockMode != LockModeStrict {
+					logger.Detail("WARNING: 'Pipfile.lock' is out of date with 'Pipfile', regenerate 'Pipfile.lock' by running 'pipenv lock'")
+				} else {
+					return packit.DetectResult{}, fmt.Errorf("'Pipfile.lock' is out of date with 'Pipfile': regenerate 'Pipfile.lock' by running 'pipenv lock'")
+				}
+			}
+
+			cpythonVersion, err := parsePythonVersion(pipfileLockParser, context.WorkingDir, "Pipfile.lock", logger)
+			if err != nil {
+				return packit.DetectResult{}, err
+			}
+
+			pipfileVersion, err := parsePythonVersion(pipfileParser, context.WorkingDir, "Pipfile", logger)
 			if err != nil {
-				if !errors.Is(err, os.ErrNotExist) {
-					return packit.DetectResult{}, err
+				return packit.DetectResult{}, err
+			}
+
+			if cpythonVersion != "" && pipfileVersion != "" && !pythonVersionsCompatible(pipfileVersion, cpythonVersion) {
+				if versionCheck != "warn" {
+					return packit.DetectResult{}, fmt.Errorf("'Pipfile' requires python '%s' but 'Pipfile.lock' requires python '%s': regenerate 'Pipfile.lock' by running 'pipenv lock'", pipfileVersion, cpythonVersion)
 				}
+
+				logger.Detail("WARNING: 'Pipfile' requires python '%s' but 'Pipfile.lock' requires python '%s', using '%s'", pipfileVersion, cpythonVersion, cpythonVersion)
 			}
 
 			if cpythonVersion != "" {
@@ -72,11 +160,9 @@ func Detect(pipfileParser, pipfileLockParser Parser) packit.DetectFunc {
 				}
 			}
 		} else {
-			cpythonVersion, err := pipfileParser.ParseVersion(context.WorkingDir)
+			cpythonVersion, err := parsePythonVersion(pipfileParser, context.WorkingDir, "Pipfile", logger)
 			if err != nil {
-				if !errors.Is(err, os.ErrNotExist) {
-					return packit.DetectResult{}, err
-				}
+				return packit.DetectResult{}, err
 			}
 
 			if cpythonVersion != "" {
@@ -106,3 +192,66 @@ func Detect(pipfileParser, pipfileLockParser Parser) packit.DetectFunc {
 		}, nil
 	}
 }
+
+// parsePythonVersion returns the python_full_version declared in the given
+// file when present, and its python_version otherwise. A warning is logged
+// when both are declared but disagree.
+func parsePythonVersion(parser Parser, path, file string, logger scribe.Emitter) (string, error) {
+	version, err := parser.ParseVersion(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return "", err
+	}
+
+	fullVersion, err := parser.ParseFullVersion(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return "", err
+	}
+
+	if fullVersion == "" {
+		return version, nil
+	}
+
+	if version != "" && fullVersion != version && !strings.HasPrefix(fullVersion, version+".") {
+		logger.Detail("WARNING: '%s' python_full_version '%s' does not match python_version '%s', using '%s'", file, fullVersion, version, fullVersion)
+	}
+
+	return fullVersion, nil
+}
+
+var pep440Release = regexp.MustCompile(`^v?(\d+(?:\.\d+)*)(.*)$`)
+
+// pythonVersionsCompatible reports whether two python version requirements
+// can be satisfied by the same interpreter. Release segments are compared
+// numerically as in PEP 440, and a shorter version such as "3.11" is treated
+// as a prefix matching any longer one such as "3.11.7".
+func pythonVersionsCompatible(left, right string) bool {
+	leftMatch := pep440Release.FindStringSubmatch(strings.ToLower(strings.TrimSpace(left)))
+	rightMatch := pep440Release.FindStringSubmatch(strings.ToLower(strings.TrimSpace(right)))
+	if leftMatch == nil || rightMatch == nil {
+		return left == right
+	}
+
+	leftRelease := strings.Split(leftMatch[1], ".")
+	rightRelease := strings.Split(rightMatch[1], ".")
+
+	length := len(leftRelease)
+	if len(rightRelease) < length {
+		length = len(rightRelease)
+	}
+
+	for i := 0; i < length; i++ {
+		l, _ := strconv.Atoi(leftRelease[i])
+		r, _ := strconv.Atoi(rightRelease[i])
+		if l != r {
+			return false
+		}
+	}
+
+	// Pre-release, post-release and local suffixes only apply to the segment
+	// they follow, so they must agree when both releases are equally precise.
+	if len(leftRelease) == len(rightRelease) {
+		return leftMatch[2] == rightMatch[2]
+	}
+
+	return true
+}
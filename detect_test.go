@@ -1,11 +1,14 @@
 package pipenvinstall_test
 
 import (
+	"bytes"
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
 
 	"github.com/paketo-buildpacks/packit/v2"
+	"github.com/paketo-buildpacks/packit/v2/scribe"
 	pipenvinstall "github.com/paketo-buildpacks/pipenv-install"
 	"github.com/paketo-buildpacks/pipenv-install/fakes"
 	"github.com/sclevine/spec"
@@ -19,6 +22,9 @@ func testDetect(t *testing.T, context spec.G, it spec.S) {
 		detect        packit.DetectFunc
 		lockParser    *fakes.Parser
 		pipfileParser *fakes.Parser
+		categories    *fakes.CategoriesParser
+		syncChecker   *fakes.LockSyncChecker
+		buffer        *bytes.Buffer
 		workingDir    string
 	)
 
@@ -32,8 +38,15 @@ func testDetect(t *testing.T, context spec.G, it spec.S) {
 
 		pipfileParser = &fakes.Parser{}
 		lockParser = &fakes.Parser{}
+		categories = &fakes.CategoriesParser{}
+		categories.ParseCategoriesCall.Returns.Categories = []string{"dev-packages", "packages", "worker"}
 
-		detect = pipenvinstall.Detect(pipfileParser, lockParser)
+		syncChecker = &fakes.LockSyncChecker{}
+		syncChecker.CheckSyncCall.Returns.InSync = true
+
+		buffer = bytes.NewBuffer(nil)
+
+		detect = pipenvinstall.Detect(pipfileParser, lockParser, categories, syncChecker, scribe.NewEmitter(buffer))
 	})
 
 	context("detection", func() {
@@ -63,6 +76,83 @@ func testDetect(t *testing.T, context spec.G, it spec.S) {
 				},
 			}))
 			Expect(pipfileParser.ParseVersionCall.Receives.Path).To(Equal(workingDir))
+			Expect(categories.ParseCategoriesCall.CallCount).To(Equal(0))
+		})
+
+		context("when BP_PIPENV_CATEGORIES is set", func() {
+			it.Before(func() {
+				Expect(os.Setenv("BP_PIPENV_CATEGORIES", "packages,worker")).To(Succeed())
+			})
+
+			it.After(func() {
+				Expect(os.Unsetenv("BP_PIPENV_CATEGORIES")).To(Succeed())
+			})
+
+			it("validates the categories against the Pipfile", func() {
+				_, err := detect(packit.DetectContext{
+					WorkingDir: workingDir,
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(categories.ParseCategoriesCall.Receives.Path).To(Equal(workingDir))
+			})
+
+			context("when a category is not declared in the Pipfile", func() {
+				it.Before(func() {
+					Expect(os.Setenv("BP_PIPENV_CATEGORIES", "packages web")).To(Succeed())
+				})
+
+				it("returns an error", func() {
+					_, err := detect(packit.DetectContext{
+						WorkingDir: workingDir,
+					})
+					Expect(err).To(MatchError("BP_PIPENV_CATEGORIES: category 'web' is not declared in 'Pipfile' (declared categories: dev-packages, packages, worker)"))
+				})
+			})
+		})
+
+		context("when the Pipfile declares a python_full_version", func() {
+			it.Before(func() {
+				pipfileParser.ParseVersionCall.Returns.Version = "3.11"
+				pipfileParser.ParseFullVersionCall.Returns.Version = "3.11.7"
+			})
+
+			it("requires the full cpython version", func() {
+				result, err := detect(packit.DetectContext{
+					WorkingDir: workingDir,
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(result.Plan.Requires[0]).To(Equal(packit.BuildPlanRequirement{
+					Name: pipenvinstall.CPython,
+					Metadata: pipenvinstall.BuildPlanMetadata{
+						Build:         true,
+						Version:       "3.11.7",
+						VersionSource: "Pipfile",
+					},
+				}))
+				Expect(pipfileParser.ParseFullVersionCall.Receives.Path).To(Equal(workingDir))
+				Expect(buffer.String()).To(BeEmpty())
+			})
+
+			context("when python_version and python_full_version disagree", func() {
+				it.Before(func() {
+					pipfileParser.ParseVersionCall.Returns.Version = "3.12"
+				})
+
+				it("uses the full version with a warning", func() {
+					result, err := detect(packit.DetectContext{
+						WorkingDir: workingDir,
+					})
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(result.Plan.Requires[0].Metadata).To(Equal(pipenvinstall.BuildPlanMetadata{
+						Build:         true,
+						Version:       "3.11.7",
+						VersionSource: "Pipfile",
+					}))
+					Expect(buffer.String()).To(ContainSubstring("WARNING: 'Pipfile' python_full_version '3.11.7' does not match python_version '3.12', using '3.11.7'"))
+				})
+			})
 		})
 
 		context("when there is no Pipfile", func() {
@@ -94,10 +184,268 @@ func testDetect(t *testing.T, context spec.G, it spec.S) {
 				})
 				Expect(err).NotTo(HaveOccurred())
 				Expect(lockParser.ParseVersionCall.Receives.Path).To(Equal(workingDir))
+				Expect(syncChecker.CheckSyncCall.Receives.Path).To(Equal(workingDir))
+			})
+
+			context("when the Pipfile and Pipfile.lock python versions are compatible", func() {
+				it.Before(func() {
+					pipfileParser.ParseVersionCall.Returns.Version = "3.11"
+					lockParser.ParseVersionCall.Returns.Version = "3.11"
+					lockParser.ParseFullVersionCall.Returns.Version = "3.11.7"
+				})
+
+				it("passes detection", func() {
+					result, err := detect(packit.DetectContext{
+						WorkingDir: workingDir,
+					})
+					Expect(err).NotTo(HaveOccurred())
+					Expect(result.Plan.Requires[0].Metadata).To(Equal(pipenvinstall.BuildPlanMetadata{
+						Build:         true,
+						Version:       "3.11.7",
+						VersionSource: "Pipfile.lock",
+					}))
+					Expect(pipfileParser.ParseVersionCall.Receives.Path).To(Equal(workingDir))
+				})
+
+				context("when BP_PIPENV_PYTHON_VERSION_CHECK is invalid", func() {
+					it.Before(func() {
+						Expect(os.Setenv("BP_PIPENV_PYTHON_VERSION_CHECK", "warnn")).To(Succeed())
+					})
+
+					it.After(func() {
+						Expect(os.Unsetenv("BP_PIPENV_PYTHON_VERSION_CHECK")).To(Succeed())
+					})
+
+					it("returns an error", func() {
+						_, err := detect(packit.DetectContext{
+							WorkingDir: workingDir,
+						})
+						Expect(err).To(MatchError(`invalid BP_PIPENV_PYTHON_VERSION_CHECK value "warnn": must be one of 'fail' or 'warn'`))
+					})
+				})
+			})
+
+			context("when the Pipfile and Pipfile.lock python versions conflict", func() {
+				it.Before(func() {
+					pipfileParser.ParseVersionCall.Returns.Version = "3.12"
+					lockParser.ParseVersionCall.Returns.Version = "3.10"
+				})
+
+				it("returns an error", func() {
+					_, err := detect(packit.DetectContext{
+						WorkingDir: workingDir,
+					})
+					Expect(err).To(MatchError("'Pipfile' requires python '3.12' but 'Pipfile.lock' requires python '3.10': regenerate 'Pipfile.lock' by running 'pipenv lock'"))
+				})
+
+				context("when BP_PIPENV_PYTHON_VERSION_CHECK is warn", func() {
+					it.Before(func() {
+						Expect(os.Setenv("BP_PIPENV_PYTHON_VERSION_CHECK", "warn")).To(Succeed())
+					})
+
+					it.After(func() {
+						Expect(os.Unsetenv("BP_PIPENV_PYTHON_VERSION_CHECK")).To(Succeed())
+					})
+
+					it("uses the Pipfile.lock version with a warning", func() {
+						result, err := detect(packit.DetectContext{
+							WorkingDir: workingDir,
+						})
+						Expect(err).NotTo(HaveOccurred())
+						Expect(result.Plan.Requires[0].Metadata).To(Equal(pipenvinstall.BuildPlanMetadata{
+							Build:         true,
+							Version:       "3.10",
+							VersionSource: "Pipfile.lock",
+						}))
+						Expect(buffer.String()).To(ContainSubstring("WARNING: 'Pipfile' requires python '3.12' but 'Pipfile.lock' requires python '3.10', using '3.10'"))
+					})
+				})
+
+				context("when BP_PIPENV_PYTHON_VERSION_CHECK is invalid", func() {
+					it.Before(func() {
+						Expect(os.Setenv("BP_PIPENV_PYTHON_VERSION_CHECK", "never")).To(Succeed())
+					})
+
+					it.After(func() {
+						Expect(os.Unsetenv("BP_PIPENV_PYTHON_VERSION_CHECK")).To(Succeed())
+					})
+
+					it("returns an error", func() {
+						_, err := detect(packit.DetectContext{
+							WorkingDir: workingDir,
+						})
+						Expect(err).To(MatchError(`invalid BP_PIPENV_PYTHON_VERSION_CHECK value "never": must be one of 'fail' or 'warn'`))
+					})
+				})
+			})
+
+			context("when the python versions differ only in patch precision", func() {
+				it.Before(func() {
+					pipfileParser.ParseVersionCall.Returns.Version = "3.11.0"
+					lockParser.ParseVersionCall.Returns.Version = "3.11.7"
+				})
+
+				it("returns an error", func() {
+					_, err := detect(packit.DetectContext{
+						WorkingDir: workingDir,
+					})
+					Expect(err).To(MatchError(ContainSubstring("'Pipfile' requires python '3.11.0' but 'Pipfile.lock' requires python '3.11.7'")))
+				})
+			})
+
+			context("when the Pipfile.lock declares a python_full_version", func() {
+				it.Before(func() {
+					lockParser.ParseVersionCall.Returns.Version = "3.11"
+					lockParser.ParseFullVersionCall.Returns.Version = "3.11.7"
+				})
+
+				it("requires the full cpython version", func() {
+					result, err := detect(packit.DetectContext{
+						WorkingDir: workingDir,
+					})
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(result.Plan.Requires[0].Metadata).To(Equal(pipenvinstall.BuildPlanMetadata{
+						Build:         true,
+						Version:       "3.11.7",
+						VersionSource: "Pipfile.lock",
+					}))
+				})
+			})
+
+			context("when the Pipfile.lock is out of sync with the Pipfile", func() {
+				it.Before(func() {
+					syncChecker.CheckSyncCall.Returns.InSync = false
+				})
+
+				it("returns an error", func() {
+					_, err := detect(packit.DetectContext{
+						WorkingDir: workingDir,
+					})
+					Expect(err).To(MatchError("'Pipfile.lock' is out of date with 'Pipfile': regenerate 'Pipfile.lock' by running 'pipenv lock'"))
+				})
+
+				context("when BP_PIPENV_LOCK_CHECK is warn", func() {
+					it.Before(func() {
+						Expect(os.Setenv("BP_PIPENV_LOCK_CHECK", "warn")).To(Succeed())
+					})
+
+					it.After(func() {
+						Expect(os.Unsetenv("BP_PIPENV_LOCK_CHECK")).To(Succeed())
+					})
+
+					it("passes detection with a warning", func() {
+						_, err := detect(packit.DetectContext{
+							WorkingDir: workingDir,
+						})
+						Expect(err).NotTo(HaveOccurred())
+						Expect(buffer.String()).To(ContainSubstring("WARNING: 'Pipfile.lock' is out of date with 'Pipfile', regenerate 'Pipfile.lock' by running 'pipenv lock'"))
+					})
+				})
+
+				context("when BP_PIPENV_LOCK_MODE is relock", func() {
+					it.Before(func() {
+						Expect(os.Setenv("BP_PIPENV_LOCK_MODE", "relock")).To(Succeed())
+					})
+
+					it.After(func() {
+						Expect(os.Unsetenv("BP_PIPENV_LOCK_MODE")).To(Succeed())
+					})
+
+					it("passes detection with a warning", func() {
+						_, err := detect(packit.DetectContext{
+							WorkingDir: workingDir,
+						})
+						Expect(err).NotTo(HaveOccurred())
+						Expect(buffer.String()).To(ContainSubstring("WARNING: 'Pipfile.lock' is out of date with 'Pipfile'"))
+					})
+				})
+			})
+
+			context("when BP_PIPENV_LOCK_CHECK is invalid", func() {
+				it.Before(func() {
+					Expect(os.Setenv("BP_PIPENV_LOCK_CHECK", "sometimes")).To(Succeed())
+				})
+
+				it.After(func() {
+					Expect(os.Unsetenv("BP_PIPENV_LOCK_CHECK")).To(Succeed())
+				})
+
+				it("returns an error", func() {
+					_, err := detect(packit.DetectContext{
+						WorkingDir: workingDir,
+					})
+					Expect(err).To(MatchError(`invalid BP_PIPENV_LOCK_CHECK value "sometimes": must be one of 'fail' or 'warn'`))
+				})
+			})
+
+			context("when the sync check fails", func() {
+				it.Before(func() {
+					syncChecker.CheckSyncCall.Returns.Err = errors.New("some-sync-error")
+				})
+
+				it("returns an error", func() {
+					_, err := detect(packit.DetectContext{
+						WorkingDir: workingDir,
+					})
+					Expect(err).To(MatchError("some-sync-error"))
+				})
 			})
 		})
 
 		context("failure cases", func() {
+			context("when the Pipfile categories cannot be parsed", func() {
+				it.Before(func() {
+					Expect(os.Setenv("BP_PIPENV_CATEGORIES", "worker")).To(Succeed())
+					categories.ParseCategoriesCall.Returns.Err = errors.New("some-categories-error")
+				})
+
+				it.After(func() {
+					Expect(os.Unsetenv("BP_PIPENV_CATEGORIES")).To(Succeed())
+				})
+
+				it("returns an error", func() {
+					_, err := detect(packit.DetectContext{
+						WorkingDir: workingDir,
+					})
+					Expect(err).To(MatchError("some-categories-error"))
+				})
+			})
+
+			context("when BP_PIPENV_LOCK_CHECK is invalid and there is no Pipfile.lock", func() {
+				it.Before(func() {
+					Expect(os.Setenv("BP_PIPENV_LOCK_CHECK", "sometimes")).To(Succeed())
+				})
+
+				it.After(func() {
+					Expect(os.Unsetenv("BP_PIPENV_LOCK_CHECK")).To(Succeed())
+				})
+
+				it("returns an error", func() {
+					_, err := detect(packit.DetectContext{
+						WorkingDir: workingDir,
+					})
+					Expect(err).To(MatchError(`invalid BP_PIPENV_LOCK_CHECK value "sometimes": must be one of 'fail' or 'warn'`))
+				})
+			})
+
+			context("when BP_PIPENV_PYTHON_VERSION_CHECK is invalid and there is no Pipfile.lock", func() {
+				it.Before(func() {
+					Expect(os.Setenv("BP_PIPENV_PYTHON_VERSION_CHECK", "never")).To(Succeed())
+				})
+
+				it.After(func() {
+					Expect(os.Unsetenv("BP_PIPENV_PYTHON_VERSION_CHECK")).To(Succeed())
+				})
+
+				it("returns an error", func() {
+					_, err := detect(packit.DetectContext{
+						WorkingDir: workingDir,
+					})
+					Expect(err).To(MatchError(`invalid BP_PIPENV_PYTHON_VERSION_CHECK value "never": must be one of 'fail' or 'warn'`))
+				})
+			})
+
 			context("when the Pipfile cannot be read", func() {
 				it.Before(func() {
 					Expect(os.Chmod(workingDir, 0000)).To(Succeed())
@@ -0,0 +1,29 @@
+package fakes
+
+import "sync"
+
+type CategoriesParser struct {
+	ParseCategoriesCall struct {
+		mutex     sync.Mutex
+		CallCount int
+		Receives  struct {
+			Path string
+		}
+		Returns struct {
+			Categories []string
+			Err        error
+		}
+		Stub func(string) ([]string, error)
+	}
+}
+
+func (f *CategoriesParser) ParseCategories(param1 string) ([]string, error) {
+	f.ParseCategoriesCall.mutex.Lock()
+	defer f.ParseCategoriesCall.mutex.Unlock()
+	f.ParseCategoriesCall.CallCount++
+	f.ParseCategoriesCall.Receives.Path = param1
+	if f.ParseCategoriesCall.Stub != nil {
+		return f.ParseCategoriesCall.Stub(param1)
+	}
+	return f.ParseCategoriesCall.Returns.Categories, f.ParseCategoriesCall.Returns.Err
+}
@@ -14,23 +14,25 @@ type InstallProcess struct {
 			WorkingDir  string
 			TargetLayer packit.Layer
 			CacheLayer  packit.Layer
+			Dev         bool
 		}
 		Returns struct {
 			Error error
 		}
-		Stub func(string, packit.Layer, packit.Layer) error
+		Stub func(string, packit.Layer, packit.Layer, bool) error
 	}
 }
 
-func (f *InstallProcess) Execute(param1 string, param2 packit.Layer, param3 packit.Layer) error {
+func (f *InstallProcess) Execute(param1 string, param2 packit.Layer, param3 packit.Layer, param4 bool) error {
 	f.ExecuteCall.mutex.Lock()
 	defer f.ExecuteCall.mutex.Unlock()
 	f.ExecuteCall.CallCount++
 	f.ExecuteCall.Receives.WorkingDir = param1
 	f.ExecuteCall.Receives.TargetLayer = param2
 	f.ExecuteCall.Receives.CacheLayer = param3
+	f.ExecuteCall.Receives.Dev = param4
 	if f.ExecuteCall.Stub != nil {
-		return f.ExecuteCall.Stub(param1, param2, param3)
+		return f.ExecuteCall.Stub(param1, param2, param3, param4)
 	}
 	return f.ExecuteCall.Returns.Error
 }
@@ -0,0 +1,51 @@
+package fakes
+
+import "sync"
+
+type LockHashParser struct {
+	CheckSyncCall struct {
+		mutex     sync.Mutex
+		CallCount int
+		Receives  struct {
+			Path string
+		}
+		Returns struct {
+			InSync bool
+			Err    error
+		}
+		Stub func(string) (bool, error)
+	}
+	ParseHashCall struct {
+		mutex     sync.Mutex
+		CallCount int
+		Receives  struct {
+			Path string
+		}
+		Returns struct {
+			Hash string
+			Err  error
+		}
+		Stub func(string) (string, error)
+	}
+}
+
+func (f *LockHashParser) CheckSync(param1 string) (bool, error) {
+	f.CheckSyncCall.mutex.Lock()
+	defer f.CheckSyncCall.mutex.Unlock()
+	f.CheckSyncCall.CallCount++
+	f.CheckSyncCall.Receives.Path = param1
+	if f.CheckSyncCall.Stub != nil {
+		return f.CheckSyncCall.Stub(param1)
+	}
+	return f.CheckSyncCall.Returns.InSync, f.CheckSyncCall.Returns.Err
+}
+func (f *LockHashParser) ParseHash(param1 string) (string, error) {
+	f.ParseHashCall.mutex.Lock()
+	defer f.ParseHashCall.mutex.Unlock()
+	f.ParseHashCall.CallCount++
+	f.ParseHashCall.Receives.Path = param1
+	if f.ParseHashCall.Stub != nil {
+		return f.ParseHashCall.Stub(param1)
+	}
+	return f.ParseHashCall.Returns.Hash, f.ParseHashCall.Returns.Err
+}
@@ -0,0 +1,29 @@
+package fakes
+
+import "sync"
+
+type LockSyncChecker struct {
+	CheckSyncCall struct {
+		mutex     sync.Mutex
+		CallCount int
+		Receives  struct {
+			Path string
+		}
+		Returns struct {
+			InSync bool
+			Err    error
+		}
+		Stub func(string) (bool, error)
+	}
+}
+
+func (f *LockSyncChecker) CheckSync(param1 string) (bool, error) {
+	f.CheckSyncCall.mutex.Lock()
+	defer f.CheckSyncCall.mutex.Unlock()
+	f.CheckSyncCall.CallCount++
+	f.CheckSyncCall.Receives.Path = param1
+	if f.CheckSyncCall.Stub != nil {
+		return f.CheckSyncCall.Stub(param1)
+	}
+	return f.CheckSyncCall.Returns.InSync, f.CheckSyncCall.Returns.Err
+}
@@ -3,6 +3,18 @@ package fakes
 import "sync"
 
 type Parser struct {
+	ParseFullVersionCall struct {
+		mutex     sync.Mutex
+		CallCount int
+		Receives  struct {
+			Path string
+		}
+		Returns struct {
+			Version string
+			Err     error
+		}
+		Stub func(string) (string, error)
+	}
 	ParseVersionCall struct {
 		mutex     sync.Mutex
 		CallCount int
@@ -17,6 +29,16 @@ type Parser struct {
 	}
 }
 
+func (f *Parser) ParseFullVersion(param1 string) (string, error) {
+	f.ParseFullVersionCall.mutex.Lock()
+	defer f.ParseFullVersionCall.mutex.Unlock()
+	f.ParseFullVersionCall.CallCount++
+	f.ParseFullVersionCall.Receives.Path = param1
+	if f.ParseFullVersionCall.Stub != nil {
+		return f.ParseFullVersionCall.Stub(param1)
+	}
+	return f.ParseFullVersionCall.Returns.Version, f.ParseFullVersionCall.Returns.Err
+}
 func (f *Parser) ParseVersion(param1 string) (string, error) {
 	f.ParseVersionCall.mutex.Lock()
 	defer f.ParseVersionCall.mutex.Unlock()
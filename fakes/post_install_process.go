@@ -0,0 +1,38 @@
+package fakes
+
+import (
+	"sync"
+
+	"github.com/paketo-buildpacks/packit/v2"
+)
+
+type PostInstallProcess struct {
+	ExecuteCall struct {
+		mutex     sync.Mutex
+		CallCount int
+		Receives  struct {
+			WorkingDir  string
+			Script      string
+			TargetLayer packit.Layer
+			VenvDir     string
+		}
+		Returns struct {
+			Error error
+		}
+		Stub func(string, string, packit.Layer, string) error
+	}
+}
+
+func (f *PostInstallProcess) Execute(param1 string, param2 string, param3 packit.Layer, param4 string) error {
+	f.ExecuteCall.mutex.Lock()
+	defer f.ExecuteCall.mutex.Unlock()
+	f.ExecuteCall.CallCount++
+	f.ExecuteCall.Receives.WorkingDir = param1
+	f.ExecuteCall.Receives.Script = param2
+	f.ExecuteCall.Receives.TargetLayer = param3
+	f.ExecuteCall.Receives.VenvDir = param4
+	if f.ExecuteCall.Stub != nil {
+		return f.ExecuteCall.Stub(param1, param2, param3, param4)
+	}
+	return f.ExecuteCall.Returns.Error
+}
@@ -0,0 +1,25 @@
+package fakes
+
+import "sync"
+
+type PythonVersionProcess struct {
+	ExecuteCall struct {
+		mutex     sync.Mutex
+		CallCount int
+		Returns   struct {
+			Version string
+			Err     error
+		}
+		Stub func() (string, error)
+	}
+}
+
+func (f *PythonVersionProcess) Execute() (string, error) {
+	f.ExecuteCall.mutex.Lock()
+	defer f.ExecuteCall.mutex.Unlock()
+	f.ExecuteCall.CallCount++
+	if f.ExecuteCall.Stub != nil {
+		return f.ExecuteCall.Stub()
+	}
+	return f.ExecuteCall.Returns.Version, f.ExecuteCall.Returns.Err
+}
@@ -0,0 +1,29 @@
+package fakes
+
+import "sync"
+
+type ScriptsParser struct {
+	ParseScriptsCall struct {
+		mutex     sync.Mutex
+		CallCount int
+		Receives  struct {
+			Path string
+		}
+		Returns struct {
+			Scripts map[string]string
+			Err     error
+		}
+		Stub func(string) (map[string]string, error)
+	}
+}
+
+func (f *ScriptsParser) ParseScripts(param1 string) (map[string]string, error) {
+	f.ParseScriptsCall.mutex.Lock()
+	defer f.ParseScriptsCall.mutex.Unlock()
+	f.ParseScriptsCall.CallCount++
+	f.ParseScriptsCall.Receives.Path = param1
+	if f.ParseScriptsCall.Stub != nil {
+		return f.ParseScriptsCall.Stub(param1)
+	}
+	return f.ParseScriptsCall.Returns.Scripts, f.ParseScriptsCall.Returns.Err
+}
@@ -7,7 +7,7 @@ type SitePackagesProcess struct {
 		mutex     sync.Mutex
 		CallCount int
 		Receives  struct {
-			LayerPath string
+			VenvDir string
 		}
 		Returns struct {
 			SitePackagesPath string
@@ -21,7 +21,7 @@ func (f *SitePackagesProcess) Execute(param1 string) (string, error) {
 	f.ExecuteCall.mutex.Lock()
 	defer f.ExecuteCall.mutex.Unlock()
 	f.ExecuteCall.CallCount++
-	f.ExecuteCall.Receives.LayerPath = param1
+	f.ExecuteCall.Receives.VenvDir = param1
 	if f.ExecuteCall.Stub != nil {
 		return f.ExecuteCall.Stub(param1)
 	}
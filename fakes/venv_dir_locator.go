@@ -7,23 +7,25 @@ type VenvDirLocator struct {
 		mutex     sync.Mutex
 		CallCount int
 		Receives  struct {
-			Path string
+			Path       string
+			WorkingDir string
 		}
 		Returns struct {
 			VenvDir string
 			Err     error
 		}
-		Stub func(string) (string, error)
+		Stub func(string, string) (string, error)
 	}
 }
 
-func (f *VenvDirLocator) LocateVenvDir(param1 string) (string, error) {
+func (f *VenvDirLocator) LocateVenvDir(param1 string, param2 string) (string, error) {
 	f.LocateVenvDirCall.mutex.Lock()
 	defer f.LocateVenvDirCall.mutex.Unlock()
 	f.LocateVenvDirCall.CallCount++
 	f.LocateVenvDirCall.Receives.Path = param1
+	f.LocateVenvDirCall.Receives.WorkingDir = param2
 	if f.LocateVenvDirCall.Stub != nil {
-		return f.LocateVenvDirCall.Stub(param1)
+		return f.LocateVenvDirCall.Stub(param1, param2)
 	}
 	return f.LocateVenvDirCall.Returns.VenvDir, f.LocateVenvDirCall.Returns.Err
 }
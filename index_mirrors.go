@@ -0,0 +1,255 @@
+package pipenvinstall
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// pypiURLs are the URLs of the PyPI index that pipenv replaces with
+// $PIPENV_PYPI_MIRROR, including the default source of a Pipfile without any.
+var pypiURLs = []string{
+	"https://pypi.org/simple",
+	"https://pypi.python.org/simple",
+}
+
+// indexMirror maps the URL of a package index source to the URL of its
+// mirror.
+type indexMirror struct {
+	Source string
+	Mirror string
+}
+
+// parseIndexMirrors parses the comma and/or whitespace separated
+// <source url>=<mirror url> entries of $BP_PIPENV_INDEX_MIRRORS.
+func parseIndexMirrors(value string) ([]indexMirror, error) {
+	var mirrors []indexMirror
+	for _, entry := range splitEnvNames(value) {
+		source, mirror, ok := strings.Cut(entry, "=")
+		if !ok || !absoluteURL(source) || !absoluteURL(mirror) {
+			return nil, fmt.Errorf("invalid BP_PIPENV_INDEX_MIRRORS entry %q: must be <source url>=<mirror url>", entry)
+		}
+
+		mirrors = append(mirrors, indexMirror{
+			Source: strings.TrimSuffix(source, "/"),
+			Mirror: mirror,
+		})
+	}
+
+	return mirrors, nil
+}
+
+func absoluteURL(value string) bool {
+	uri, err := url.Parse(value)
+	return err == nil && uri.Scheme != "" && uri.Host != ""
+}
+
+// applyIndexMirrors writes copies of workingDir/Pipfile and
+// workingDir/Pipfile.lock with the source URLs replaced by their mirrors to a
+// temporary directory, so that the credentials a mirror URL may hold never
+// end up in the app. When Pipfile.lock is in sync with Pipfile, the _meta.hash
+// of the copy is updated to match the rewritten Pipfile, so that it still
+// installs with --deploy; the package hashes are left as they are. It returns
+// the environment that points pipenv at the copies and at the mirror of PyPI,
+// and a function that removes the copies. A Pipfile.lock that pipenv writes
+// in the meantime is first written back to workingDir with the source URLs.
+func (p PipenvInstallProcess) applyIndexMirrors(workingDir string, mirrors []indexMirror) (cleanup func() error, env []string, err error) {
+	if len(mirrors) == 0 {
+		return func() error { return nil }, nil, nil
+	}
+
+	pipfile, err := os.ReadFile(filepath.Join(workingDir, "Pipfile"))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read Pipfile: %w", err)
+	}
+
+	lockExists := true
+	lock, err := os.ReadFile(filepath.Join(workingDir, "Pipfile.lock"))
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			return nil, nil, fmt.Errorf("failed to read Pipfile.lock: %w", err)
+		}
+		lockExists = false
+	}
+
+	var lockHash, pipfileHash string
+	if lockExists {
+		lockHash, err = NewPipfileLockParser().ParseHash(workingDir)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to parse Pipfile.lock: %w", err)
+		}
+
+		pipfileHash, err = CalculatePipfileHash(workingDir)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to calculate Pipfile hash: %w", err)
+		}
+	}
+
+	rewrittenPipfile := pipfile
+	rewrittenLock := lock
+	for _, mirror := range mirrors {
+		var inPipfile, inLock bool
+		rewrittenPipfile, inPipfile = replaceQuotedURL(rewrittenPipfile, mirror, `"`, `'`)
+		rewrittenLock, inLock = replaceQuotedURL(rewrittenLock, mirror, `"`)
+
+		// pipenv also falls back to PyPI when the Pipfile declares no source.
+		isPyPI := containsURL(pypiURLs, mirror.Source)
+		if isPyPI {
+			env = append(env, fmt.Sprintf("PIPENV_PYPI_MIRROR=%s", mirror.Mirror))
+		}
+
+		if inPipfile || inLock || isPyPI {
+			p.logger.Subprocess("Using index mirror %s for %s", Redact(mirror.Mirror), Redact(mirror.Source))
+		}
+	}
+
+	dir, err := os.MkdirTemp("", "pipenv-index-mirrors")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create index mirrors directory: %w", err)
+	}
+
+	err = os.WriteFile(filepath.Join(dir, "Pipfile"), rewrittenPipfile, 0600)
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, nil, fmt.Errorf("failed to write Pipfile: %w", err)
+	}
+
+	if lockExists {
+		if lockHash == pipfileHash {
+			rewrittenHash, err := CalculatePipfileHash(dir)
+			if err != nil {
+				os.RemoveAll(dir)
+				return nil, nil, fmt.Errorf("failed to calculate Pipfile hash: %w", err)
+			}
+
+			rewrittenLock = bytes.Replace(rewrittenLock, []byte(`"`+lockHash+`"`), []byte(`"`+rewrittenHash+`"`), 1)
+		}
+
+		err = os.WriteFile(filepath.Join(dir, "Pipfile.lock"), rewrittenLock, 0600)
+		if err != nil {
+			os.RemoveAll(dir)
+			return nil, nil, fmt.Errorf("failed to write Pipfile.lock: %w", err)
+		}
+	}
+
+	cleanup = func() error {
+		defer os.RemoveAll(dir)
+
+		written, err := os.ReadFile(filepath.Join(dir, "Pipfile.lock"))
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return nil
+			}
+
+			return fmt.Errorf("failed to read Pipfile.lock: %w", err)
+		}
+
+		if lockExists && bytes.Equal(written, rewrittenLock) {
+			return nil
+		}
+
+		return unmirrorLock(workingDir, dir, written, pipfile, mirrors)
+	}
+
+	env = append(env, fmt.Sprintf("PIPENV_PIPFILE=%s", filepath.Join(dir, "Pipfile")))
+
+	return cleanup, env, nil
+}
+
+// unmirrorLock writes the lock that pipenv wrote into the mirrorsDir back to
+// workingDir/Pipfile.lock, with its mirror URLs replaced by the source URLs as
+// they appear in pipfile, the original Pipfile content. When the lock is in
+// sync with the rewritten Pipfile, its _meta.hash is updated to match the
+// original one. The mode of an existing workingDir/Pipfile.lock is kept.
+func unmirrorLock(workingDir, mirrorsDir string, lock, pipfile []byte, mirrors []indexMirror) error {
+	lockHash, err := NewPipfileLockParser().ParseHash(mirrorsDir)
+	if err != nil {
+		return fmt.Errorf("failed to parse Pipfile.lock: %w", err)
+	}
+
+	rewrittenHash, err := CalculatePipfileHash(mirrorsDir)
+	if err != nil {
+		return fmt.Errorf("failed to calculate Pipfile hash: %w", err)
+	}
+
+	for _, mirror := range mirrors {
+		source := mirror.Source
+		if bytes.Contains(pipfile, []byte(`"`+source+`/"`)) || bytes.Contains(pipfile, []byte(`'`+source+`/'`)) {
+			source += "/"
+		}
+
+		lock = bytes.ReplaceAll(lock, []byte(`"`+mirror.Mirror+`"`), []byte(`"`+source+`"`))
+	}
+
+	if lockHash == rewrittenHash {
+		pipfileHash, err := CalculatePipfileHash(workingDir)
+		if err != nil {
+			return fmt.Errorf("failed to calculate Pipfile hash: %w", err)
+		}
+
+		lock = bytes.Replace(lock, []byte(`"`+lockHash+`"`), []byte(`"`+pipfileHash+`"`), 1)
+	}
+
+	lockPath := filepath.Join(workingDir, "Pipfile.lock")
+
+	var mode os.FileMode = 0644
+	info, err := os.Stat(lockPath)
+	if err == nil {
+		mode = info.Mode().Perm()
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to stat Pipfile.lock: %w", err)
+	}
+
+	err = os.WriteFile(lockPath, lock, mode)
+	if err != nil {
+		return fmt.Errorf("failed to write Pipfile.lock: %w", err)
+	}
+
+	return nil
+}
+
+// joinCleanupError returns err with the error of cleaning up the index mirrors
+// added to it, so that neither is lost.
+func joinCleanupError(err, cleanupErr error) error {
+	if cleanupErr == nil {
+		return err
+	}
+
+	if err == nil {
+		return cleanupErr
+	}
+
+	return fmt.Errorf("%w\n%s", err, cleanupErr)
+}
+
+// replaceQuotedURL replaces the quoted occurrences of the mirror source URL,
+// with or without a trailing slash, in content. It reports whether any were
+// replaced.
+func replaceQuotedURL(content []byte, mirror indexMirror, quotes ...string) ([]byte, bool) {
+	replaced := false
+	for _, quote := range quotes {
+		for _, source := range []string{mirror.Source, mirror.Source + "/"} {
+			old := []byte(quote + source + quote)
+			if bytes.Contains(content, old) {
+				content = bytes.ReplaceAll(content, old, []byte(quote+mirror.Mirror+quote))
+				replaced = true
+			}
+		}
+	}
+
+	return content, replaced
+}
+
+func containsURL(urls []string, value string) bool {
+	for _, u := range urls {
+		if u == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -14,7 +14,11 @@ func TestUnitPipenvInstall(t *testing.T) {
 	suite("InstallProcess", testInstallProcess)
 	suite("LockParser", testLockParser)
 	suite("PipfileParser", testPipfileParser)
+	suite("Redact", testRedact)
+	suite("ScriptProcess", testScriptProcess)
 	suite("SitePackagesProcess", testSiteProcess)
+	suite("VenvInterpreter", testVenvInterpreter)
 	suite("VenvLocator", testVenvLocator)
+	suite("VersionProcess", testVersionProcess)
 	suite.Run(t)
 }
@@ -4,11 +4,14 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/paketo-buildpacks/packit/v2"
+	"github.com/paketo-buildpacks/packit/v2/fs"
 	"github.com/paketo-buildpacks/packit/v2/pexec"
 	"github.com/paketo-buildpacks/packit/v2/scribe"
 )
@@ -35,67 +38,208 @@ func NewPipenvInstallProcess(executable Executable, logger scribe.Emitter) Pipen
 }
 
 // Execute installs the pipenv dependencies from workingDir/Pipfile into the
-// targetLayer. The cacheLayer is used for the pipenv cache directory.
-func (p PipenvInstallProcess) Execute(workingDir string, targetLayer, cacheLayer packit.Layer) error {
+// targetLayer. The cacheLayer is used for the pipenv cache directory. When dev
+// is true the dev-packages are installed as well. Only the categories listed
+// in $BP_PIPENV_CATEGORIES are installed when it is set. The flags passed to
+// pipenv depend on the version reported by `pipenv --version`. When the app
+// does not ship a Pipfile.lock and $BP_PIPENV_GENERATE_LOCK is true, one is
+// generated (or restored from the cacheLayer) and installed from with --deploy.
+// How an existing Pipfile.lock is installed from is selected by
+// $BP_PIPENV_LOCK_MODE. The virtual env is named with $PIPENV_CUSTOM_VENV_NAME
+// and any other virtual env in the targetLayer is removed. At the DEBUG log
+// level pipenv runs with --verbose and its output is streamed to the log.
+// Only the build environment variables allowed by DefaultPipenvEnvAllowlist
+// and $BP_PIPENV_ENV_PASSTHROUGH are passed on to pipenv. With
+// $BP_PIPENV_INDEX_MIRRORS, pipenv runs against a copy of the Pipfile that uses
+// the mirrors of its sources.
+func (p PipenvInstallProcess) Execute(workingDir string, targetLayer, cacheLayer packit.Layer, dev bool) error {
 	targetPath := targetLayer.Path
 	cachePath := cacheLayer.Path
 	lockExists := true
-	args := []string{
-		"install",
-		// --deploy is for checking Pipefile and lock are in sync
-		"--deploy",
-		// --system is not used because it does not let us write to a specific path
-	}
 
 	_, err := os.Stat(filepath.Join(workingDir, "Pipfile.lock"))
 	if err != nil {
 		if errors.Is(err, os.ErrNotExist) {
 			lockExists = false
-			args = []string{
-				"install",
-				// Do not write out a Pipfile.lock. It's not useful and is expensive.
-				"--skip-lock",
-			}
 		} else {
 			return fmt.Errorf("failed to stat Pipfile.lock: %w", err)
 		}
 	}
 
-	p.logger.Subprocess("Running 'pipenv %s'", strings.Join(args, " "))
+	generateLock, err := generateLockEnabled()
+	if err != nil {
+		return err
+	}
+
+	lockMode, err := parseLockMode()
+	if err != nil {
+		return err
+	}
+
+	mirrors, err := parseIndexMirrors(os.Getenv("BP_PIPENV_INDEX_MIRRORS"))
+	if err != nil {
+		return err
+	}
+
+	venv, err := venvName(workingDir)
+	if err != nil {
+		return err
+	}
+
+	lockGenerated := false
+	if !lockExists && generateLock {
+		err = p.restoreOrGenerateLock(workingDir, targetPath, cachePath, venv, mirrors)
+		if err != nil {
+			return err
+		}
+		lockExists = true
+		lockGenerated = true
+	}
+
+	cleanup, env, err := p.applyIndexMirrors(workingDir, mirrors)
+	if err != nil {
+		return err
+	}
+
+	err = withEnv(env, func() error {
+		return p.install(workingDir, targetPath, cachePath, venv, lockMode, lockExists, lockGenerated, dev)
+	})
+	err = joinCleanupError(err, cleanup())
+	if err != nil {
+		return err
+	}
 
+	if lockGenerated {
+		// Keep a copy of the lock the packages were installed from next to them.
+		err = fs.Copy(filepath.Join(workingDir, "Pipfile.lock"), filepath.Join(targetPath, "Pipfile.lock"))
+		if err != nil {
+			return fmt.Errorf("failed to copy Pipfile.lock into packages layer: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// install runs `pipenv install` and `pipenv clean` for Execute.
+func (p PipenvInstallProcess) install(workingDir, targetPath, cachePath, venv, lockMode string, lockExists, lockGenerated, dev bool) error {
 	buffer := bytes.NewBuffer(nil)
+	err := p.executable.Execute(pexec.Execution{
+		Args:   []string{"--version"},
+		Env:    p.env(),
+		Dir:    workingDir,
+		Stdout: buffer,
+		Stderr: buffer,
+	})
+	if err != nil {
+		return fmt.Errorf("pipenv --version failed:\n%s\nerror: %w", buffer.String(), err)
+	}
+
+	version, err := parsePipenvVersion(buffer.String())
+	if err != nil {
+		return err
+	}
+
+	flags, err := lookupPipenvFlags(version)
+	if err != nil {
+		return err
+	}
+
+	args := []string{"install"}
+	if lockExists {
+		p.logger.Subprocess("Using lock mode '%s'", lockMode)
+
+		switch lockMode {
+		case LockModeRelock:
+			if !lockGenerated {
+				err = p.lock(workingDir, targetPath, cachePath, venv)
+				if err != nil {
+					return err
+				}
+			}
+		case LockModeIgnore:
+			// --ignore-pipfile installs exactly what is in the lock
+			args = append(args, "--ignore-pipfile")
+		}
+
+		if lockMode != LockModeIgnore && flags.Deploy {
+			// --deploy is for checking Pipefile and lock are in sync
+			args = append(args, "--deploy")
+		}
+		// --system is not used because it does not let us write to a specific path
+	} else if flags.SkipLock {
+		// Do not write out a Pipfile.lock. It's not useful and is expensive.
+		args = append(args, "--skip-lock")
+	}
+
+	categories := splitCategories(os.Getenv("BP_PIPENV_CATEGORIES"))
+	if len(categories) > 0 {
+		if !flags.Categories {
+			return fmt.Errorf("pipenv %s does not support BP_PIPENV_CATEGORIES: --categories requires pipenv 2022.10.4 or later", version)
+		}
+
+		// --dev is ignored by pipenv when --categories is given
+		if dev {
+			categories = append(categories, "dev-packages")
+		}
+		args = append(args, "--categories", strings.Join(categories, " "))
+	} else if dev {
+		args = append(args, "--dev")
+	}
+
+	if debugEnabled() {
+		args = append(args, "--verbose")
+	}
+
+	p.logger.Subprocess("Running 'pipenv %s'", strings.Join(args, " "))
+
+	buffer.Reset()
+	output := p.output(buffer)
 	err = p.executable.Execute(pexec.Execution{
 		Args: args,
-		Env: append(os.Environ(),
+		Env: p.env(
 			// Pipenv seems to disregard PYTHONUSERBASE.
 			// Target dir set using WORKON_HOME which is a virtualenv setting.
 			"PIP_USER=1",
 			"PIP_IGNORE_INSTALLED=1",
 			fmt.Sprintf("WORKON_HOME=%s", targetPath),
+			fmt.Sprintf("PIPENV_CUSTOM_VENV_NAME=%s", venv),
 			fmt.Sprintf("PIPENV_CACHE_DIR=%s", cachePath)),
 		Dir:    workingDir,
-		Stdout: buffer,
-		Stderr: buffer,
+		Stdout: output,
+		Stderr: output,
 	})
 
 	if err != nil {
-		return fmt.Errorf("pipenv install failed:\n%s\nerror: %w", buffer.String(), err)
+		return newPipenvError("install", buffer.String(), err)
+	}
+
+	// A reused layer may still hold the virtual env of a previous project name.
+	err = removeStaleVenvs(targetPath, venv)
+	if err != nil {
+		return err
 	}
 
 	// if clean is run when no lock file exists, it will generate
-	// one, which is an expensive operation.
-	if lockExists {
-		p.logger.Subprocess("Running 'pipenv clean'")
+	// one, which is an expensive operation. Releases without --skip-lock
+	// have already written one during install.
+	if lockExists || flags.CleanWithoutLock {
+		cleanArgs := []string{"clean"}
+		if debugEnabled() {
+			cleanArgs = append(cleanArgs, "--verbose")
+		}
+
+		p.logger.Subprocess("Running 'pipenv %s'", strings.Join(cleanArgs, " "))
 		buffer.Reset()
 		err = p.executable.Execute(pexec.Execution{
-			Args: []string{"clean"},
-			Env: append(os.Environ(),
+			Args: cleanArgs,
+			Env: p.env(
 				"PIP_USER=1",
 				fmt.Sprintf("WORKON_HOME=%s", targetPath),
+				fmt.Sprintf("PIPENV_CUSTOM_VENV_NAME=%s", venv),
 				fmt.Sprintf("PIPENV_CACHE_DIR=%s", cachePath)),
 			Dir:    workingDir,
-			Stdout: buffer,
-			Stderr: buffer,
+			Stdout: output,
+			Stderr: output,
 		})
 		if err != nil {
 			return fmt.Errorf("pipenv clean failed:\n%s\nerror: %w", buffer.String(), err)
@@ -104,3 +248,143 @@ func (p PipenvInstallProcess) Execute(workingDir string, targetLayer, cacheLayer
 
 	return nil
 }
+
+// restoreOrGenerateLock places a Pipfile.lock into the workingDir. A lock
+// generated by a previous build for the same Pipfile content is restored from
+// the cache, otherwise `pipenv lock` is run and its result is cached. The lock
+// is generated from the index mirrors, but records the source URLs of the
+// Pipfile.
+func (p PipenvInstallProcess) restoreOrGenerateLock(workingDir, targetPath, cachePath, venv string, mirrors []indexMirror) error {
+	pipfileSHA, err := fs.NewChecksumCalculator().Sum(filepath.Join(workingDir, "Pipfile"))
+	if err != nil {
+		return fmt.Errorf("failed to checksum Pipfile: %w", err)
+	}
+
+	lockPath := filepath.Join(workingDir, "Pipfile.lock")
+	cachedLockPath := filepath.Join(cachePath, "locks", pipfileSHA, "Pipfile.lock")
+
+	exists, err := fs.Exists(cachedLockPath)
+	if err != nil {
+		return err
+	}
+
+	if exists {
+		p.logger.Subprocess("Using cached Pipfile.lock generated for Pipfile sha256:%s", pipfileSHA)
+		err = fs.Copy(cachedLockPath, lockPath)
+		if err != nil {
+			return fmt.Errorf("failed to restore cached Pipfile.lock: %w", err)
+		}
+
+		return nil
+	}
+
+	cleanup, env, err := p.applyIndexMirrors(workingDir, mirrors)
+	if err != nil {
+		return err
+	}
+
+	err = withEnv(env, func() error {
+		return p.lock(workingDir, targetPath, cachePath, venv)
+	})
+	err = joinCleanupError(err, cleanup())
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(cachedLockPath), os.ModePerm)
+	if err != nil {
+		return fmt.Errorf("failed to cache Pipfile.lock: %w", err)
+	}
+
+	err = fs.Copy(lockPath, cachedLockPath)
+	if err != nil {
+		return fmt.Errorf("failed to cache Pipfile.lock: %w", err)
+	}
+
+	return nil
+}
+
+// lock runs `pipenv lock` to write a Pipfile.lock for the workingDir/Pipfile.
+func (p PipenvInstallProcess) lock(workingDir, targetPath, cachePath, venv string) error {
+	args := []string{"lock"}
+	if debugEnabled() {
+		args = append(args, "--verbose")
+	}
+
+	p.logger.Subprocess("Running 'pipenv %s'", strings.Join(args, " "))
+
+	buffer := bytes.NewBuffer(nil)
+	output := p.output(buffer)
+	err := p.executable.Execute(pexec.Execution{
+		Args: args,
+		Env: p.env(
+			fmt.Sprintf("WORKON_HOME=%s", targetPath),
+			fmt.Sprintf("PIPENV_CUSTOM_VENV_NAME=%s", venv),
+			fmt.Sprintf("PIPENV_CACHE_DIR=%s", cachePath)),
+		Dir:    workingDir,
+		Stdout: output,
+		Stderr: output,
+	})
+	if err != nil {
+		return newPipenvError("lock", buffer.String(), err)
+	}
+
+	return nil
+}
+
+// output returns the writer pipenv output is sent to. The output is kept in
+// the buffer to report on failure, and streamed to the debug log.
+func (p PipenvInstallProcess) output(buffer *bytes.Buffer) io.Writer {
+	return io.MultiWriter(buffer, p.logger.Debug.ActionWriter)
+}
+
+// env returns the environment pipenv is run with, and logs the names of its
+// variables at the DEBUG log level.
+func (p PipenvInstallProcess) env(vars ...string) []string {
+	env := pipenvEnv(vars...)
+	p.logger.Debug.Subprocess("Passing environment variables: %s", strings.Join(envNames(env), ", "))
+
+	return env
+}
+
+// debugEnabled reports whether $BP_LOG_LEVEL selects debug logging, in which
+// case pipenv is run with --verbose.
+func debugEnabled() bool {
+	return strings.ToUpper(os.Getenv("BP_LOG_LEVEL")) == "DEBUG"
+}
+
+// parseLockMode returns the lock mode selected by $BP_PIPENV_LOCK_MODE,
+// defaulting to LockModeStrict.
+func parseLockMode() (string, error) {
+	mode := os.Getenv("BP_PIPENV_LOCK_MODE")
+	switch mode {
+	case "":
+		return LockModeStrict, nil
+	case LockModeStrict, LockModeRelock, LockModeIgnore:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("invalid BP_PIPENV_LOCK_MODE value %q: must be one of '%s', '%s' or '%s'", mode, LockModeStrict, LockModeRelock, LockModeIgnore)
+	}
+}
+
+// generateLockEnabled reports whether $BP_PIPENV_GENERATE_LOCK opts into
+// generating a Pipfile.lock for apps that do not ship one.
+func generateLockEnabled() (bool, error) {
+	return parseBoolEnv("BP_PIPENV_GENERATE_LOCK")
+}
+
+// parseBoolEnv parses the boolean value of the named environment variable,
+// defaulting to false when it is unset or empty.
+func parseBoolEnv(name string) (bool, error) {
+	value, ok := os.LookupEnv(name)
+	if !ok || value == "" {
+		return false, nil
+	}
+
+	enabled, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse %s value %q: %w", name, value, err)
+	}
+
+	return enabled, nil
+}
@@ -78,12 +78,20 @@ func testDefault(t *testing.T, context spec.G, it spec.S) {
 			))
 			Expect(logs).To(ContainLines(
 				"  Configuring build environment",
-				MatchRegexp(fmt.Sprintf(`    PATH       -> "/layers/%s/packages/[\w_-]+/bin:\$PATH"`, strings.ReplaceAll(buildpackInfo.Buildpack.ID, "/", "_"))),
-				MatchRegexp(fmt.Sprintf(`    PYTHONPATH -> "/layers/%s/packages/lib/python\d+\.\d+/site-packages:\$PYTHONPATH"`, strings.ReplaceAll(buildpackInfo.Buildpack.ID, "/", "_"))),
+				MatchRegexp(fmt.Sprintf(`    PATH                    -> "/layers/%s/packages/[\w_-]+/bin:\$PATH"`, strings.ReplaceAll(buildpackInfo.Buildpack.ID, "/", "_"))),
+				`    PIPENV_ACTIVE           -> "1"`,
+				MatchRegexp(`    PIPENV_CUSTOM_VENV_NAME -> "[\w_-]+"`),
+				MatchRegexp(fmt.Sprintf(`    PYTHONPATH              -> "/layers/%s/packages/[\w_-]+/lib/python\d+\.\d+/site-packages:\$PYTHONPATH"`, strings.ReplaceAll(buildpackInfo.Buildpack.ID, "/", "_"))),
+				MatchRegexp(fmt.Sprintf(`    VIRTUAL_ENV             -> "/layers/%s/packages/[\w_-]+"`, strings.ReplaceAll(buildpackInfo.Buildpack.ID, "/", "_"))),
+				MatchRegexp(fmt.Sprintf(`    WORKON_HOME             -> "/layers/%s/packages"`, strings.ReplaceAll(buildpackInfo.Buildpack.ID, "/", "_"))),
 				"",
 				"  Configuring launch environment",
-				MatchRegexp(fmt.Sprintf(`    PATH       -> "/layers/%s/packages/[\w_-]+/bin:\$PATH"`, strings.ReplaceAll(buildpackInfo.Buildpack.ID, "/", "_"))),
-				MatchRegexp(fmt.Sprintf(`    PYTHONPATH -> "/layers/%s/packages/lib/python\d+\.\d+/site-packages:\$PYTHONPATH"`, strings.ReplaceAll(buildpackInfo.Buildpack.ID, "/", "_"))),
+				MatchRegexp(fmt.Sprintf(`    PATH                    -> "/layers/%s/packages/[\w_-]+/bin:\$PATH"`, strings.ReplaceAll(buildpackInfo.Buildpack.ID, "/", "_"))),
+				`    PIPENV_ACTIVE           -> "1"`,
+				MatchRegexp(`    PIPENV_CUSTOM_VENV_NAME -> "[\w_-]+"`),
+				MatchRegexp(fmt.Sprintf(`    PYTHONPATH              -> "/layers/%s/packages/[\w_-]+/lib/python\d+\.\d+/site-packages:\$PYTHONPATH"`, strings.ReplaceAll(buildpackInfo.Buildpack.ID, "/", "_"))),
+				MatchRegexp(fmt.Sprintf(`    VIRTUAL_ENV             -> "/layers/%s/packages/[\w_-]+"`, strings.ReplaceAll(buildpackInfo.Buildpack.ID, "/", "_"))),
+				MatchRegexp(fmt.Sprintf(`    WORKON_HOME             -> "/layers/%s/packages"`, strings.ReplaceAll(buildpackInfo.Buildpack.ID, "/", "_"))),
 			))
 			Expect(logs).To(ContainLines(
 				// Due to Pipfile requirement
@@ -145,6 +153,7 @@ func testDefault(t *testing.T, context spec.G, it spec.S) {
 			Expect(logs).To(ContainLines(
 				MatchRegexp(fmt.Sprintf(`%s \d+\.\d+\.\d+`, buildpackInfo.Buildpack.Name)),
 				"  Executing build process",
+				"    Using lock mode 'strict'",
 				MatchRegexp("    Running 'pipenv install --deploy"),
 				MatchRegexp("    Running 'pipenv clean"),
 			))
@@ -1,9 +1,16 @@
 package pipenvinstall
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
+	"unicode/utf16"
+
+	"github.com/pelletier/go-toml"
 )
 
 type PipfileLockParser struct{}
@@ -12,32 +19,252 @@ func NewPipfileLockParser() PipfileLockParser {
 	return PipfileLockParser{}
 }
 
-func (p PipfileLockParser) ParseVersion(path string) (version string, err error) {
+// PipfileLock is the content of a Pipfile.lock.
+type PipfileLock struct {
+	// Meta holds the _meta section of the lock.
+	Meta PipfileLockMeta `json:"_meta"`
+
+	// Default holds the locked packages of the [packages] category.
+	Default map[string]PipfileLockPackage `json:"default"`
+
+	// Develop holds the locked packages of the [dev-packages] category.
+	Develop map[string]PipfileLockPackage `json:"develop"`
+
+	// Categories holds the locked packages of any custom category, keyed by
+	// category name.
+	Categories map[string]map[string]PipfileLockPackage `json:"-"`
+}
 
-	pipFileLock, err := os.Open(filepath.Join(path, "Pipfile.lock"))
+// PipfileLockMeta is the _meta section of a Pipfile.lock.
+type PipfileLockMeta struct {
+	Hash struct {
+		SHA256 string `json:"sha256"`
+	} `json:"hash"`
+	PipfileSpec int                 `json:"pipfile-spec"`
+	Requires    PipfileLockRequires `json:"requires"`
+	Sources     []PipfileLockSource `json:"sources"`
+}
+
+// PipfileLockRequires is the python requirement recorded in a Pipfile.lock.
+type PipfileLockRequires struct {
+	PythonVersion     string `json:"python_version"`
+	PythonFullVersion string `json:"python_full_version"`
+}
+
+// PipfileLockSource is a package index recorded in a Pipfile.lock.
+type PipfileLockSource struct {
+	Name      string `json:"name"`
+	URL       string `json:"url"`
+	VerifySSL bool   `json:"verify_ssl"`
+}
+
+// PipfileLockPackage is a single locked package in a Pipfile.lock.
+type PipfileLockPackage struct {
+	Version      string   `json:"version,omitempty"`
+	Hashes       []string `json:"hashes,omitempty"`
+	Markers      string   `json:"markers,omitempty"`
+	Index        string   `json:"index,omitempty"`
+	Extras       []string `json:"extras,omitempty"`
+	Git          string   `json:"git,omitempty"`
+	Ref          string   `json:"ref,omitempty"`
+	Subdirectory string   `json:"subdirectory,omitempty"`
+	Path         string   `json:"path,omitempty"`
+	File         string   `json:"file,omitempty"`
+	Editable     bool     `json:"editable,omitempty"`
+}
+
+// UnmarshalJSON decodes a Pipfile.lock, collecting every top-level section
+// other than _meta, default and develop into Categories.
+func (l *PipfileLock) UnmarshalJSON(data []byte) error {
+	var sections map[string]json.RawMessage
+	err := json.Unmarshal(data, &sections)
 	if err != nil {
-		return "", err
+		return err
 	}
 
-	var PipfileLock struct {
-		Meta struct {
-			Requires struct {
-				Version string `json:"python_version"`
-			} `json:"requires"`
-			Sources []struct {
-				URL string
+	for name, section := range sections {
+		switch name {
+		case "_meta":
+			err = json.Unmarshal(section, &l.Meta)
+		case "default":
+			err = json.Unmarshal(section, &l.Default)
+		case "develop":
+			err = json.Unmarshal(section, &l.Develop)
+		default:
+			var packages map[string]PipfileLockPackage
+			err = json.Unmarshal(section, &packages)
+			if err == nil {
+				if l.Categories == nil {
+					l.Categories = map[string]map[string]PipfileLockPackage{}
+				}
+				l.Categories[name] = packages
 			}
-		} `json:"_meta"`
-		Default map[string]struct {
-			Version string
+		}
+		if err != nil {
+			return fmt.Errorf("failed to decode %q section: %w", name, err)
 		}
 	}
 
-	err = json.NewDecoder(pipFileLock).Decode(&PipfileLock)
+	return nil
+}
 
+func (p PipfileLockParser) ParseVersion(path string) (version string, err error) {
+	lock, err := p.Parse(path)
 	if err != nil {
 		return "", err
 	}
 
-	return PipfileLock.Meta.Requires.Version, nil
+	return lock.Meta.Requires.PythonVersion, nil
+}
+
+// ParseFullVersion returns the python_full_version declared in Pipfile.lock.
+func (p PipfileLockParser) ParseFullVersion(path string) (version string, err error) {
+	lock, err := p.Parse(path)
+	if err != nil {
+		return "", err
+	}
+
+	return lock.Meta.Requires.PythonFullVersion, nil
+}
+
+// ParseHash returns the Pipfile content hash that pipenv records under
+// _meta.hash.sha256 in Pipfile.lock.
+func (p PipfileLockParser) ParseHash(path string) (hash string, err error) {
+	lock, err := p.Parse(path)
+	if err != nil {
+		return "", err
+	}
+
+	return lock.Meta.Hash.SHA256, nil
+}
+
+// CheckSync reports whether the _meta.hash.sha256 recorded in Pipfile.lock
+// matches the content hash of the Pipfile, as calculated by pipenv.
+func (p PipfileLockParser) CheckSync(path string) (inSync bool, err error) {
+	lockHash, err := p.ParseHash(path)
+	if err != nil {
+		return false, err
+	}
+
+	pipfileHash, err := CalculatePipfileHash(path)
+	if err != nil {
+		return false, err
+	}
+
+	return lockHash == pipfileHash, nil
+}
+
+// Parse decodes path/Pipfile.lock.
+func (p PipfileLockParser) Parse(path string) (PipfileLock, error) {
+	file, err := os.Open(filepath.Join(path, "Pipfile.lock"))
+	if err != nil {
+		return PipfileLock{}, err
+	}
+	defer file.Close()
+
+	var lock PipfileLock
+	err = json.NewDecoder(file).Decode(&lock)
+	if err != nil {
+		return PipfileLock{}, err
+	}
+
+	return lock, nil
+}
+
+// pipfileNonCategorySections are the Pipfile tables that are not package
+// categories and therefore do not contribute to the Pipfile hash.
+var pipfileNonCategorySections = map[string]bool{
+	"source":       true,
+	"requires":     true,
+	"scripts":      true,
+	"pipenv":       true,
+	"packages":     true,
+	"dev-packages": true,
+	"default":      true,
+	"develop":      true,
+}
+
+// CalculatePipfileHash calculates the content hash of path/Pipfile the same
+// way pipenv does when writing _meta.hash.sha256 into Pipfile.lock: the sha256
+// of the sources, requirements and package categories serialized as compact,
+// key-sorted, ASCII-only JSON.
+func CalculatePipfileHash(path string) (string, error) {
+	fp, err := os.Open(filepath.Join(path, "Pipfile"))
+	if err != nil {
+		return "", err
+	}
+	defer fp.Close()
+
+	var pipfile map[string]interface{}
+	err = toml.NewDecoder(fp).Decode(&pipfile)
+	if err != nil {
+		return "", err
+	}
+
+	sources, ok := pipfile["source"]
+	if !ok {
+		sources = []interface{}{
+			map[string]interface{}{
+				"name":       "pypi",
+				"url":        "https://pypi.org/simple",
+				"verify_ssl": true,
+			},
+		}
+	}
+
+	data := map[string]interface{}{
+		"_meta": map[string]interface{}{
+			"sources":  sources,
+			"requires": valueOrEmpty(pipfile["requires"]),
+		},
+		"default": valueOrEmpty(pipfile["packages"]),
+		"develop": valueOrEmpty(pipfile["dev-packages"]),
+	}
+
+	for name, value := range pipfile {
+		if !pipfileNonCategorySections[name] {
+			data[name] = value
+		}
+	}
+
+	buffer := bytes.NewBuffer(nil)
+	encoder := json.NewEncoder(buffer)
+	encoder.SetEscapeHTML(false)
+	err = encoder.Encode(data)
+	if err != nil {
+		return "", fmt.Errorf("failed to serialize Pipfile: %w", err)
+	}
+
+	sum := sha256.Sum256(asciiJSON(bytes.TrimSuffix(buffer.Bytes(), []byte("\n"))))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func valueOrEmpty(value interface{}) interface{} {
+	if value == nil {
+		return map[string]interface{}{}
+	}
+
+	return value
+}
+
+// asciiJSON escapes every non-ASCII character as \uXXXX, matching the
+// ensure_ascii output of the python json module.
+func asciiJSON(content []byte) []byte {
+	var buffer bytes.Buffer
+	for _, r := range string(content) {
+		if r < 0x80 {
+			buffer.WriteRune(r)
+			continue
+		}
+
+		if r > 0xFFFF {
+			high, low := utf16.EncodeRune(r)
+			fmt.Fprintf(&buffer, "\\u%04x\\u%04x", high, low)
+			continue
+		}
+
+		fmt.Fprintf(&buffer, "\\u%04x", r)
+	}
+
+	return buffer.Bytes()
 }
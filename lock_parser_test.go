@@ -26,6 +26,124 @@ func testLockParser(t *testing.T, context spec.G, it spec.S) {
 		parser = pipenvinstall.NewPipfileLockParser()
 	})
 
+	context("Calling Parse", func() {
+		it.Before(func() {
+			Expect(os.WriteFile(filepath.Join(workingDir, "Pipfile.lock"), []byte(`{
+    "_meta": {
+        "hash": {
+            "sha256": "6f803d4df721681c56a93ac01ee9234098df1c5aa13b1543ae64c4d77ea38a87"
+        },
+        "pipfile-spec": 6,
+        "requires": {
+            "python_version": "3.11",
+            "python_full_version": "3.11.7"
+        },
+        "sources": [
+            {
+                "name": "pypi",
+                "url": "https://pypi.org/simple",
+                "verify_ssl": true
+            }
+        ]
+    },
+    "default": {
+        "flask": {
+            "hashes": [
+                "sha256:aaaa",
+                "sha256:bbbb"
+            ],
+            "index": "pypi",
+            "markers": "python_version >= '3.8'",
+            "version": "==3.0.0"
+        },
+        "mylib": {
+            "editable": true,
+            "path": "./mylib"
+        },
+        "requests": {
+            "extras": ["socks"],
+            "git": "https://github.com/psf/requests.git",
+            "ref": "abc123"
+        }
+    },
+    "develop": {
+        "pytest": {
+            "version": "==7.4.3"
+        }
+    },
+    "worker": {
+        "celery": {
+            "version": "==5.3.6"
+        }
+    }
+}`), os.ModePerm)).To(Succeed())
+		})
+
+		it.After(func() {
+			Expect(os.Remove(filepath.Join(workingDir, "Pipfile.lock"))).To(Succeed())
+		})
+
+		it("parses the full CPython version", func() {
+			version, err := parser.ParseFullVersion(workingDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(version).To(Equal("3.11.7"))
+		})
+
+		it("parses the complete lock", func() {
+			lock, err := parser.Parse(workingDir)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(lock.Meta.Hash.SHA256).To(Equal("6f803d4df721681c56a93ac01ee9234098df1c5aa13b1543ae64c4d77ea38a87"))
+			Expect(lock.Meta.PipfileSpec).To(Equal(6))
+			Expect(lock.Meta.Requires).To(Equal(pipenvinstall.PipfileLockRequires{
+				PythonVersion:     "3.11",
+				PythonFullVersion: "3.11.7",
+			}))
+			Expect(lock.Meta.Sources).To(Equal([]pipenvinstall.PipfileLockSource{
+				{Name: "pypi", URL: "https://pypi.org/simple", VerifySSL: true},
+			}))
+
+			Expect(lock.Default).To(Equal(map[string]pipenvinstall.PipfileLockPackage{
+				"flask": {
+					Version: "==3.0.0",
+					Hashes:  []string{"sha256:aaaa", "sha256:bbbb"},
+					Markers: "python_version >= '3.8'",
+					Index:   "pypi",
+				},
+				"mylib": {
+					Path:     "./mylib",
+					Editable: true,
+				},
+				"requests": {
+					Extras: []string{"socks"},
+					Git:    "https://github.com/psf/requests.git",
+					Ref:    "abc123",
+				},
+			}))
+			Expect(lock.Develop).To(Equal(map[string]pipenvinstall.PipfileLockPackage{
+				"pytest": {Version: "==7.4.3"},
+			}))
+			Expect(lock.Categories).To(Equal(map[string]map[string]pipenvinstall.PipfileLockPackage{
+				"worker": {
+					"celery": {Version: "==5.3.6"},
+				},
+			}))
+		})
+
+		context("failure cases", func() {
+			context("when a section is malformed", func() {
+				it.Before(func() {
+					Expect(os.WriteFile(filepath.Join(workingDir, "Pipfile.lock"), []byte(`{"default": []}`), os.ModePerm)).To(Succeed())
+				})
+
+				it("returns an error", func() {
+					_, err := parser.Parse(workingDir)
+					Expect(err).To(MatchError(ContainSubstring(`failed to decode "default" section`)))
+				})
+			})
+		})
+	})
+
 	context("Calling ParseVersion", func() {
 		context("when Pipfile.lock is valid and specifies a CPython version", func() {
 
@@ -98,4 +216,112 @@ func testLockParser(t *testing.T, context spec.G, it spec.S) {
 			})
 		})
 	})
+
+	context("Calling ParseHash", func() {
+		it.Before(func() {
+			Expect(os.WriteFile(
+				filepath.Join(workingDir, "Pipfile.lock"),
+				[]byte(`{
+    "_meta": {
+        "hash": {
+            "sha256": "6f803d4df721681c56a93ac01ee9234098df1c5aa13b1543ae64c4d77ea38a87"
+        },
+        "pipfile-spec": 6
+    }
+}`), os.ModePerm)).To(Succeed())
+		})
+
+		it.After(func() {
+			Expect(os.Remove(filepath.Join(workingDir, "Pipfile.lock"))).To(Succeed())
+		})
+
+		it("parses the Pipfile hash", func() {
+			hash, err := parser.ParseHash(workingDir)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(hash).To(Equal("6f803d4df721681c56a93ac01ee9234098df1c5aa13b1543ae64c4d77ea38a87"))
+		})
+
+		context("failure cases", func() {
+			context("when the contents of the Pipfile.lock file are malformed", func() {
+				it.Before(func() {
+					Expect(os.WriteFile(
+						filepath.Join(workingDir, "Pipfile.lock"),
+						[]byte(`%%%%%%%%`), os.ModePerm)).To(Succeed())
+				})
+
+				it("returns an error", func() {
+					_, err := parser.ParseHash(workingDir)
+					Expect(err).To(MatchError(ContainSubstring("invalid character")))
+				})
+			})
+		})
+	})
+
+	context("Calling CheckSync", func() {
+		it.Before(func() {
+			Expect(os.WriteFile(filepath.Join(workingDir, "Pipfile"), []byte(`[[source]]
+url = "https://pypi.python.org/simple"
+verify_ssl = true
+name = "app_with_lock_file"
+
+[packages]
+Flask = "==2.1.3"
+gunicorn = "*"
+itsdangerous = "==2.1.2"
+
+[dev-packages]
+tox = "*"
+coverage = "*"
+"flake8" = "*"
+flask-testing = "*"
+`), os.ModePerm)).To(Succeed())
+
+			Expect(os.WriteFile(filepath.Join(workingDir, "Pipfile.lock"), []byte(`{
+    "_meta": {
+        "hash": {
+            "sha256": "97bb61eb838332545616e426f1541b3a21ab670e91584aca4b33a5c6747e7948"
+        }
+    }
+}`), os.ModePerm)).To(Succeed())
+		})
+
+		it.After(func() {
+			Expect(os.RemoveAll(workingDir)).To(Succeed())
+		})
+
+		it("matches the hash pipenv recorded in the lock", func() {
+			inSync, err := parser.CheckSync(workingDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(inSync).To(BeTrue())
+		})
+
+		context("when the Pipfile has changed since locking", func() {
+			it.Before(func() {
+				f, err := os.OpenFile(filepath.Join(workingDir, "Pipfile"), os.O_APPEND|os.O_WRONLY, os.ModePerm)
+				Expect(err).NotTo(HaveOccurred())
+				_, err = f.WriteString("\n[requires]\npython_version = \"3.12\"\n")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(f.Close()).To(Succeed())
+			})
+
+			it("reports the lock as out of sync", func() {
+				inSync, err := parser.CheckSync(workingDir)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(inSync).To(BeFalse())
+			})
+		})
+
+		context("failure cases", func() {
+			context("when the Pipfile is malformed", func() {
+				it.Before(func() {
+					Expect(os.WriteFile(filepath.Join(workingDir, "Pipfile"), []byte(`%%%%%%%%`), os.ModePerm)).To(Succeed())
+				})
+
+				it("returns an error", func() {
+					_, err := parser.CheckSync(workingDir)
+					Expect(err).To(MatchError(ContainSubstring("parsing error")))
+				})
+			})
+		})
+	})
 }
@@ -0,0 +1,88 @@
+package pipenvinstall
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// pipenvFlags describes the command line options supported by a range of
+// pipenv releases.
+type pipenvFlags struct {
+	// SkipLock denotes that `pipenv install --skip-lock` is available to
+	// install without writing a Pipfile.lock.
+	SkipLock bool
+
+	// Deploy denotes that `pipenv install --deploy` is available to abort
+	// when the Pipfile.lock is out of date.
+	Deploy bool
+
+	// Categories denotes that `pipenv install --categories` is available to
+	// install arbitrary Pipfile package categories.
+	Categories bool
+
+	// CleanWithoutLock denotes that `pipenv install` always writes a
+	// Pipfile.lock, so `pipenv clean` can run cheaply even when the app does
+	// not ship one.
+	CleanWithoutLock bool
+}
+
+// pipenvCompatibility is the table of supported pipenv releases. Versions
+// outside of these ranges are rejected. The newest range is left open, as
+// pipenv releases are calendar versioned.
+var pipenvCompatibility = []struct {
+	constraint string
+	flags      pipenvFlags
+}{
+	{
+		constraint: ">= 2022.1.8, < 2022.10.4",
+		flags:      pipenvFlags{SkipLock: true, Deploy: true},
+	},
+	{
+		constraint: ">= 2022.10.4, < 2024.0.0",
+		flags:      pipenvFlags{SkipLock: true, Deploy: true, Categories: true},
+	},
+	{
+		// --skip-lock was removed in pipenv 2024.0.0
+		constraint: ">= 2024.0.0",
+		flags:      pipenvFlags{Deploy: true, Categories: true, CleanWithoutLock: true},
+	},
+}
+
+// parsePipenvVersion extracts the version from the output of `pipenv
+// --version`, e.g. "pipenv, version 2023.12.1".
+func parsePipenvVersion(output string) (string, error) {
+	fields := strings.Fields(output)
+	if len(fields) == 0 {
+		return "", fmt.Errorf("failed to parse pipenv version: output is empty")
+	}
+
+	return fields[len(fields)-1], nil
+}
+
+// lookupPipenvFlags returns the flags supported by the given pipenv version.
+func lookupPipenvFlags(version string) (pipenvFlags, error) {
+	v, err := semver.NewVersion(version)
+	if err != nil {
+		return pipenvFlags{}, fmt.Errorf("failed to parse pipenv version %q: %w", version, err)
+	}
+
+	for _, entry := range pipenvCompatibility {
+		constraint, err := semver.NewConstraint(entry.constraint)
+		if err != nil {
+			return pipenvFlags{}, err
+		}
+
+		if constraint.Check(v) {
+			return entry.flags, nil
+		}
+	}
+
+	var ranges []string
+	for _, entry := range pipenvCompatibility {
+		ranges = append(ranges, entry.constraint)
+	}
+
+	return pipenvFlags{}, fmt.Errorf("unsupported pipenv version %s: supported versions are %s", version, strings.Join(ranges, " || "))
+}
@@ -0,0 +1,92 @@
+package pipenvinstall
+
+import (
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// DefaultPipenvEnvAllowlist lists the environment variables passed on to
+// pipenv by default. A trailing '*' matches any suffix.
+var DefaultPipenvEnvAllowlist = []string{
+	"PATH",
+	"HOME",
+	"TMPDIR",
+	"PYTHON*",
+	"PIP_*",
+	"PIPENV_*",
+	"VIRTUALENV_*",
+
+	// proxies
+	"HTTP_PROXY", "HTTPS_PROXY", "NO_PROXY", "ALL_PROXY",
+	"http_proxy", "https_proxy", "no_proxy", "all_proxy",
+
+	// locale
+	"LANG", "LANGUAGE", "LC_*",
+
+	// package index credentials
+	"NETRC",
+
+	// CA certificates
+	"SSL_CERT_FILE", "SSL_CERT_DIR", "REQUESTS_CA_BUNDLE", "CURL_CA_BUNDLE",
+
+	// compiling packages from source against the libraries of other layers
+	"CC", "CXX", "CFLAGS", "CXXFLAGS", "CPPFLAGS", "LDFLAGS",
+	"CPATH", "LIBRARY_PATH", "LD_LIBRARY_PATH", "PKG_CONFIG_PATH",
+}
+
+// pipenvEnv returns the environment pipenv is run with: the variables of the
+// build environment matched by DefaultPipenvEnvAllowlist or
+// $BP_PIPENV_ENV_PASSTHROUGH, followed by vars.
+func pipenvEnv(vars ...string) []string {
+	allowlist := append(append([]string{}, DefaultPipenvEnvAllowlist...), splitEnvNames(os.Getenv("BP_PIPENV_ENV_PASSTHROUGH"))...)
+
+	var env []string
+	for _, variable := range os.Environ() {
+		name, _, _ := strings.Cut(variable, "=")
+		if envNameAllowed(name, allowlist) {
+			env = append(env, variable)
+		}
+	}
+
+	return append(env, vars...)
+}
+
+// envNameAllowed reports whether name is matched by an entry of the
+// allowlist.
+func envNameAllowed(name string, allowlist []string) bool {
+	for _, pattern := range allowlist {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+// envNames returns the sorted, unique variable names set by env.
+func envNames(env []string) []string {
+	seen := map[string]bool{}
+
+	var names []string
+	for _, variable := range env {
+		name, _, _ := strings.Cut(variable, "=")
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// splitEnvNames splits a list of variable names separated by commas and/or
+// whitespace.
+func splitEnvNames(value string) []string {
+	return strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
+}
@@ -0,0 +1,128 @@
+package pipenvinstall
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+// The classes of pipenv failures recognised from the pipenv output. A
+// PipenvError of a recognised class matches it with errors.Is.
+var (
+	ErrLockOutOfDate          = errors.New("'Pipfile.lock' is out of date with 'Pipfile'")
+	ErrHashMismatch           = errors.New("package hashes do not match 'Pipfile.lock'")
+	ErrResolutionConflict     = errors.New("package requirements conflict")
+	ErrNoMatchingDistribution = errors.New("no matching distribution found")
+	ErrCompilerRequired       = errors.New("building a package from source requires a compiler")
+	ErrIndexUnreachable       = errors.New("package index is unreachable")
+	ErrIndexAuthentication    = errors.New("package index authentication failed")
+)
+
+// PipenvError is returned when a pipenv command fails. When the failure is
+// recognised, Class holds its class and Summary and Hint describe it and how
+// to remediate it.
+type PipenvError struct {
+	Command string
+	Class   error
+	Summary string
+	Hint    string
+	Output  string
+	Err     error
+}
+
+func (e PipenvError) Error() string {
+	if e.Class == nil {
+		return fmt.Sprintf("pipenv %s failed:\n%s\nerror: %s", e.Command, e.Output, e.Err)
+	}
+
+	return fmt.Sprintf("pipenv %s failed: %s\nhint: %s\n%s\nerror: %s", e.Command, e.Summary, e.Hint, e.Output, e.Err)
+}
+
+func (e PipenvError) Unwrap() error {
+	return e.Err
+}
+
+func (e PipenvError) Is(target error) bool {
+	return e.Class != nil && target == e.Class
+}
+
+type pipenvFailure struct {
+	class   error
+	pattern *regexp.Regexp
+	hint    string
+
+	// subject is the index of the pattern group naming the package the
+	// failure is about, or 0 when there is none.
+	subject int
+}
+
+// pipenvFailures are checked in order, so that the more specific classes are
+// recognised before the generic ones, e.g. an authentication failure before
+// the connection errors it causes.
+var pipenvFailures = []pipenvFailure{
+	{
+		class:   ErrIndexAuthentication,
+		pattern: regexp.MustCompile(`(?i)\b(401|403) Client Error|HTTP error (401|403)|\b401 Unauthorized|\b403 Forbidden|User for \S+:`),
+		hint:    "check the credentials of the package index, e.g. in the Pipfile [[source]] url or $PIP_INDEX_URL",
+	},
+	{
+		class:   ErrLockOutOfDate,
+		pattern: regexp.MustCompile(`(?i)Pipfile\.lock \([0-9a-f]+\) (is )?out of date|Aborting deploy`),
+		hint:    "regenerate 'Pipfile.lock' by running 'pipenv lock', or set $BP_PIPENV_LOCK_MODE to 'relock'",
+	},
+	{
+		class:   ErrHashMismatch,
+		pattern: regexp.MustCompile(`(?i)DO NOT MATCH THE HASHES|hashes are required in --require-hashes mode`),
+		hint:    "regenerate 'Pipfile.lock' by running 'pipenv lock' to record the hashes of the published packages",
+	},
+	{
+		class:   ErrCompilerRequired,
+		pattern: regexp.MustCompile(`(?i)unable to execute '\S*(gcc|cc|g\+\+|clang)'|command '\S*(gcc|cc|g\+\+|clang)' failed|\b(gcc|cc|g\+\+|clang): (command )?not found|Python\.h: No such file`),
+		hint:    "depend on a version of the package that publishes a wheel for this platform, or build on a stack that provides a C compiler and the python headers",
+	},
+	{
+		class:   ErrNoMatchingDistribution,
+		pattern: regexp.MustCompile(`(?i)No matching distribution found for (\S+)`),
+		hint:    "check the package name and version, and that the package supports this python version and platform",
+		subject: 1,
+	},
+	{
+		class:   ErrResolutionConflict,
+		pattern: regexp.MustCompile(`(?i)Could not find a version that matches (\S+)|ResolutionFailure|ResolutionImpossible|conflicting dependencies`),
+		hint:    "relax the conflicting version requirements in 'Pipfile' and run 'pipenv lock'",
+		subject: 1,
+	},
+	{
+		class:   ErrIndexUnreachable,
+		pattern: regexp.MustCompile(`(?i)Max retries exceeded|NewConnectionError|Temporary failure in name resolution|Name or service not known|Connection refused|Read timed out|Could not fetch URL|CERTIFICATE_VERIFY_FAILED`),
+		hint:    "check that the package index is reachable from the build, including any proxy and CA certificate settings",
+	},
+}
+
+// newPipenvError returns the PipenvError for the failed pipenv command,
+// classifying the failure from its output.
+func newPipenvError(command, output string, err error) error {
+	pipenvErr := PipenvError{
+		Command: command,
+		Output:  output,
+		Err:     err,
+	}
+
+	for _, failure := range pipenvFailures {
+		matches := failure.pattern.FindStringSubmatch(output)
+		if matches == nil {
+			continue
+		}
+
+		pipenvErr.Class = failure.class
+		pipenvErr.Summary = failure.class.Error()
+		pipenvErr.Hint = failure.hint
+
+		if failure.subject > 0 && matches[failure.subject] != "" {
+			pipenvErr.Summary = fmt.Sprintf("%s for '%s'", pipenvErr.Summary, matches[failure.subject])
+		}
+		break
+	}
+
+	return pipenvErr
+}
@@ -1,12 +1,83 @@
 package pipenvinstall
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
 
 	"github.com/pelletier/go-toml"
 )
 
+// Pipfile is the content of a Pipfile.
+type Pipfile struct {
+	// Sources holds the [[source]] package indexes.
+	Sources []PipfileSource
+
+	// Packages holds the [packages] category. It is nil when the Pipfile
+	// does not declare the category.
+	Packages map[string]PipfilePackage
+
+	// DevPackages holds the [dev-packages] category. It is nil when the
+	// Pipfile does not declare the category.
+	DevPackages map[string]PipfilePackage
+
+	// Categories holds any custom package category, keyed by category name.
+	Categories map[string]map[string]PipfilePackage
+
+	// Requires holds the [requires] python requirement.
+	Requires PipfileRequires
+
+	// Scripts holds the [scripts] commands, keyed by script name.
+	Scripts map[string]string
+
+	// Pipenv holds the [pipenv] settings.
+	Pipenv PipfileSettings
+}
+
+// PipfileSource is a [[source]] package index declared in a Pipfile.
+type PipfileSource struct {
+	Name      string `toml:"name"`
+	URL       string `toml:"url"`
+	VerifySSL bool   `toml:"verify_ssl"`
+}
+
+// PipfileRequires is the [requires] section of a Pipfile.
+type PipfileRequires struct {
+	PythonVersion     string `toml:"python_version"`
+	PythonFullVersion string `toml:"python_full_version"`
+}
+
+// PipfileSettings is the [pipenv] section of a Pipfile.
+type PipfileSettings struct {
+	AllowPrereleases        bool `toml:"allow_prereleases"`
+	DisablePipInput         bool `toml:"disable_pip_input"`
+	InstallSearchAllSources bool `toml:"install_search_all_sources"`
+	SortPipfile             bool `toml:"sort_pipfile"`
+}
+
+// PipfilePackage is a single package requirement declared in a Pipfile,
+// either as a plain version specifier (`flask = "*"`) or as a table
+// (`flask = {version = "*", extras = ["async"]}`).
+type PipfilePackage struct {
+	Version      string
+	Extras       []string
+	Markers      string
+	Index        string
+	Git          string
+	Ref          string
+	Subdirectory string
+	Path         string
+	File         string
+	Editable     bool
+}
+
+// PipfileParser implements the Parser, CategoriesParser and ScriptsParser
+// interfaces.
 type PipfileParser struct{}
 
 func NewPipfileParser() PipfileParser {
@@ -14,22 +85,187 @@ func NewPipfileParser() PipfileParser {
 }
 
 func (p PipfileParser) ParseVersion(path string) (version string, err error) {
+	pipfile, err := p.Parse(path)
+	if err != nil {
+		return "", err
+	}
 
-	fp, err := os.Open(filepath.Join(path, "Pipfile"))
+	return pipfile.Requires.PythonVersion, nil
+}
+
+// ParseFullVersion returns the python_full_version declared in Pipfile.
+func (p PipfileParser) ParseFullVersion(path string) (version string, err error) {
+	pipfile, err := p.Parse(path)
 	if err != nil {
 		return "", err
 	}
 
-	var Pipfile struct {
-		Requires struct {
-			PythonVersion string `toml:"python_version"`
-		} `toml:"requires"`
+	return pipfile.Requires.PythonFullVersion, nil
+}
+
+// ParseCategories returns the sorted names of the package categories declared
+// in the Pipfile, e.g. "packages", "dev-packages" and any custom category.
+func (p PipfileParser) ParseCategories(path string) (categories []string, err error) {
+	pipfile, err := p.Parse(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if pipfile.Packages != nil {
+		categories = append(categories, "packages")
 	}
 
-	err = toml.NewDecoder(fp).Decode(&Pipfile)
+	if pipfile.DevPackages != nil {
+		categories = append(categories, "dev-packages")
+	}
+
+	for name := range pipfile.Categories {
+		categories = append(categories, name)
+	}
+	sort.Strings(categories)
+
+	return categories, nil
+}
+
+// ParseScripts returns the [scripts] commands declared in the Pipfile, keyed by
+// script name.
+func (p PipfileParser) ParseScripts(path string) (scripts map[string]string, err error) {
+	pipfile, err := p.Parse(path)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	return Pipfile.Requires.PythonVersion, nil
+	return pipfile.Scripts, nil
+}
+
+var tomlErrorPosition = regexp.MustCompile(`^\((\d+), (\d+)\): (.*)$`)
+
+// Parse decodes path/Pipfile. Decoding errors are reported with the line and
+// column they occurred at.
+func (p PipfileParser) Parse(path string) (Pipfile, error) {
+	fp, err := os.Open(filepath.Join(path, "Pipfile"))
+	if err != nil {
+		return Pipfile{}, err
+	}
+	defer fp.Close()
+
+	tree, err := toml.LoadReader(fp)
+	if err != nil {
+		if matches := tomlErrorPosition.FindStringSubmatch(err.Error()); matches != nil {
+			line, _ := strconv.Atoi(matches[1])
+			column, _ := strconv.Atoi(matches[2])
+			return Pipfile{}, pipfileError(toml.Position{Line: line, Col: column}, "%s", matches[3])
+		}
+		return Pipfile{}, fmt.Errorf("failed to parse Pipfile: %w", err)
+	}
+
+	var sections struct {
+		Sources  []PipfileSource `toml:"source"`
+		Requires PipfileRequires `toml:"requires"`
+		Pipenv   PipfileSettings `toml:"pipenv"`
+	}
+
+	err = tree.Unmarshal(&sections)
+	if err != nil {
+		return Pipfile{}, fmt.Errorf("failed to parse Pipfile: %w", err)
+	}
+
+	pipfile := Pipfile{
+		Sources:  sections.Sources,
+		Requires: sections.Requires,
+		Pipenv:   sections.Pipenv,
+	}
+
+	for _, name := range tree.Keys() {
+		switch name {
+		case "source", "requires", "pipenv":
+			continue
+
+		case "scripts":
+			scripts, ok := tree.Get(name).(*toml.Tree)
+			if !ok {
+				return Pipfile{}, pipfileError(tree.GetPosition(name), "[scripts] must be a table")
+			}
+
+			pipfile.Scripts = map[string]string{}
+			for _, script := range scripts.Keys() {
+				// Only plain command scripts are supported, not {call = "..."} tables.
+				if command, ok := scripts.Get(script).(string); ok {
+					pipfile.Scripts[script] = command
+				}
+			}
+
+		default:
+			category, ok := tree.Get(name).(*toml.Tree)
+			if !ok {
+				return Pipfile{}, pipfileError(tree.GetPosition(name), "[%s] must be a table", name)
+			}
+
+			packages, err := parsePipfilePackages(category)
+			if err != nil {
+				return Pipfile{}, err
+			}
+
+			switch name {
+			case "packages":
+				pipfile.Packages = packages
+			case "dev-packages":
+				pipfile.DevPackages = packages
+			default:
+				if pipfile.Categories == nil {
+					pipfile.Categories = map[string]map[string]PipfilePackage{}
+				}
+				pipfile.Categories[name] = packages
+			}
+		}
+	}
+
+	return pipfile, nil
+}
+
+func parsePipfilePackages(category *toml.Tree) (map[string]PipfilePackage, error) {
+	packages := map[string]PipfilePackage{}
+	for _, name := range category.Keys() {
+		switch value := category.Get(name).(type) {
+		case string:
+			packages[name] = PipfilePackage{Version: value}
+
+		case *toml.Tree:
+			var pkg struct {
+				Version      string   `toml:"version"`
+				Extras       []string `toml:"extras"`
+				Markers      string   `toml:"markers"`
+				Index        string   `toml:"index"`
+				Git          string   `toml:"git"`
+				Ref          string   `toml:"ref"`
+				Subdirectory string   `toml:"subdirectory"`
+				Path         string   `toml:"path"`
+				File         string   `toml:"file"`
+				Editable     bool     `toml:"editable"`
+			}
+
+			err := value.Unmarshal(&pkg)
+			if err != nil {
+				return nil, pipfileError(category.GetPosition(name), "invalid requirement for package '%s': %s", name, err)
+			}
+			packages[name] = PipfilePackage(pkg)
+
+		default:
+			return nil, pipfileError(category.GetPosition(name), "invalid requirement for package '%s': must be a string or a table", name)
+		}
+	}
+
+	return packages, nil
+}
+
+func pipfileError(position toml.Position, format string, args ...interface{}) error {
+	return fmt.Errorf("failed to parse Pipfile at line %d, column %d: %s", position.Line, position.Col, fmt.Sprintf(format, args...))
+}
+
+// splitCategories splits a list of categories separated by commas and/or
+// whitespace, the same way the pipenv --categories option does.
+func splitCategories(value string) []string {
+	return strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
 }
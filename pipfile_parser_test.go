@@ -26,6 +26,115 @@ func testPipfileParser(t *testing.T, context spec.G, it spec.S) {
 		parser = pipenvinstall.NewPipfileParser()
 	})
 
+	context("Calling Parse", func() {
+		it.Before(func() {
+			Expect(os.WriteFile(
+				filepath.Join(workingDir, "Pipfile"),
+				[]byte(`
+[[source]]
+url = "https://pypi.org/simple"
+verify_ssl = true
+name = "pypi"
+
+[[source]]
+url = "https://pypi.internal/simple"
+verify_ssl = false
+name = "internal"
+
+[packages]
+flask = "*"
+requests = {version = "==2.31.0", extras = ["socks"], index = "internal"}
+mylib = {path = "./mylib", editable = true}
+django = {git = "https://github.com/django/django.git", ref = "main", markers = "python_version >= '3.10'"}
+
+[dev-packages]
+pytest = "*"
+
+[worker]
+celery = "==5.3.6"
+
+[requires]
+python_version = "3.11"
+python_full_version = "3.11.7"
+
+[scripts]
+web = "gunicorn app:app"
+hello = {call = "app:hello"}
+
+[pipenv]
+allow_prereleases = true
+`), os.ModePerm)).To(Succeed())
+		})
+
+		it.After(func() {
+			Expect(os.Remove(filepath.Join(workingDir, "Pipfile"))).To(Succeed())
+		})
+
+		it("parses the complete Pipfile", func() {
+			pipfile, err := parser.Parse(workingDir)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(pipfile).To(Equal(pipenvinstall.Pipfile{
+				Sources: []pipenvinstall.PipfileSource{
+					{Name: "pypi", URL: "https://pypi.org/simple", VerifySSL: true},
+					{Name: "internal", URL: "https://pypi.internal/simple", VerifySSL: false},
+				},
+				Packages: map[string]pipenvinstall.PipfilePackage{
+					"flask":    {Version: "*"},
+					"requests": {Version: "==2.31.0", Extras: []string{"socks"}, Index: "internal"},
+					"mylib":    {Path: "./mylib", Editable: true},
+					"django":   {Git: "https://github.com/django/django.git", Ref: "main", Markers: "python_version >= '3.10'"},
+				},
+				DevPackages: map[string]pipenvinstall.PipfilePackage{
+					"pytest": {Version: "*"},
+				},
+				Categories: map[string]map[string]pipenvinstall.PipfilePackage{
+					"worker": {
+						"celery": {Version: "==5.3.6"},
+					},
+				},
+				Requires: pipenvinstall.PipfileRequires{
+					PythonVersion:     "3.11",
+					PythonFullVersion: "3.11.7",
+				},
+				Scripts: map[string]string{
+					"web": "gunicorn app:app",
+				},
+				Pipenv: pipenvinstall.PipfileSettings{
+					AllowPrereleases: true,
+				},
+			}))
+		})
+
+		context("failure cases", func() {
+			context("when the Pipfile is not valid TOML", func() {
+				it.Before(func() {
+					Expect(os.WriteFile(
+						filepath.Join(workingDir, "Pipfile"),
+						[]byte("[packages]\nflask = \"*\"\n%%%\n"), os.ModePerm)).To(Succeed())
+				})
+
+				it("returns an error with the line and column", func() {
+					_, err := parser.Parse(workingDir)
+					Expect(err).To(MatchError("failed to parse Pipfile at line 3, column 1: parsing error: keys cannot contain % character"))
+				})
+			})
+
+			context("when a package requirement is not a string or a table", func() {
+				it.Before(func() {
+					Expect(os.WriteFile(
+						filepath.Join(workingDir, "Pipfile"),
+						[]byte("[packages]\nflask = 3\n"), os.ModePerm)).To(Succeed())
+				})
+
+				it("returns an error with the line and column", func() {
+					_, err := parser.Parse(workingDir)
+					Expect(err).To(MatchError("failed to parse Pipfile at line 2, column 1: invalid requirement for package 'flask': must be a string or a table"))
+				})
+			})
+		})
+	})
+
 	context("Calling ParseVersion", func() {
 		context("when Pipfile is valid and specifies a CPython version", func() {
 
@@ -47,6 +156,12 @@ python_version = "3.8"
 				Expect(err).ToNot(HaveOccurred())
 				Expect(version).To(Equal("3.8"))
 			})
+
+			it("parses an empty full Python version", func() {
+				version, err := parser.ParseFullVersion(workingDir)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(version).To(BeEmpty())
+			})
 		})
 
 		context("failure cases", func() {
@@ -86,4 +201,103 @@ python_version = "3.8"
 			})
 		})
 	})
+
+	context("Calling ParseCategories", func() {
+		it.Before(func() {
+			Expect(os.WriteFile(
+				filepath.Join(workingDir, "Pipfile"),
+				[]byte(`
+[[source]]
+url = "https://pypi.org/simple"
+verify_ssl = true
+name = "pypi"
+
+[packages]
+flask = "*"
+
+[dev-packages]
+pytest = "*"
+
+[worker]
+celery = "*"
+
+[requires]
+python_version = "3.11"
+
+[scripts]
+web = "gunicorn app:app"
+
+[pipenv]
+allow_prereleases = false
+`), os.ModePerm)).To(Succeed())
+		})
+
+		it.After(func() {
+			Expect(os.Remove(filepath.Join(workingDir, "Pipfile"))).To(Succeed())
+		})
+
+		it("returns the declared package categories", func() {
+			categories, err := parser.ParseCategories(workingDir)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(categories).To(Equal([]string{"dev-packages", "packages", "worker"}))
+		})
+
+		context("failure cases", func() {
+			context("when the contents of the Pipfile file are malformed", func() {
+				it.Before(func() {
+					Expect(os.WriteFile(
+						filepath.Join(workingDir, "Pipfile"),
+						[]byte(`%%%%%%%%`), os.ModePerm)).To(Succeed())
+				})
+
+				it("returns an error", func() {
+					_, err := parser.ParseCategories(workingDir)
+					Expect(err).To(MatchError(ContainSubstring("parsing error")))
+				})
+			})
+		})
+	})
+
+	context("Calling ParseScripts", func() {
+		it.Before(func() {
+			Expect(os.WriteFile(
+				filepath.Join(workingDir, "Pipfile"),
+				[]byte(`
+[packages]
+flask = "*"
+
+[scripts]
+web = "gunicorn app:app"
+worker = "celery -A tasks worker"
+check = {call = "package.module:func()"}
+`), os.ModePerm)).To(Succeed())
+		})
+
+		it.After(func() {
+			Expect(os.Remove(filepath.Join(workingDir, "Pipfile"))).To(Succeed())
+		})
+
+		it("returns the declared command scripts", func() {
+			scripts, err := parser.ParseScripts(workingDir)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(scripts).To(Equal(map[string]string{
+				"web":    "gunicorn app:app",
+				"worker": "celery -A tasks worker",
+			}))
+		})
+
+		context("failure cases", func() {
+			context("when Pipfile file cannot be read", func() {
+				it.Before(func() {
+					Expect(os.Remove(filepath.Join(workingDir, "Pipfile"))).To(Succeed())
+					Expect(os.WriteFile(filepath.Join(workingDir, "Pipfile"), nil, 0000)).To(Succeed())
+				})
+
+				it("returns an error", func() {
+					_, err := parser.ParseScripts(workingDir)
+					Expect(err).To(MatchError(ContainSubstring("permission denied")))
+				})
+			})
+		})
+	})
 }
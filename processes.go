@@ -0,0 +1,76 @@
+package pipenvinstall
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/paketo-buildpacks/packit/v2"
+	"github.com/paketo-buildpacks/packit/v2/fs"
+)
+
+// scriptProcesses turns the Pipfile [scripts] into launch processes sorted by
+// type. The executable of each command is resolved against binDir when it is
+// installed there, the same way `pipenv run` finds it on the venv PATH. The
+// process named by defaultProcess is the default; when it is empty the "web"
+// script, if any, is the default.
+func scriptProcesses(scripts map[string]string, binDir, defaultProcess string) ([]packit.Process, error) {
+	if defaultProcess != "" {
+		if _, ok := scripts[defaultProcess]; !ok {
+			return nil, fmt.Errorf("BP_PIPENV_DEFAULT_PROCESS: script '%s' is not declared in 'Pipfile' [scripts]", defaultProcess)
+		}
+	} else if _, ok := scripts["web"]; ok {
+		defaultProcess = "web"
+	}
+
+	var names []string
+	for name := range scripts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var processes []packit.Process
+	for _, name := range names {
+		command, err := resolveScriptCommand(scripts[name], binDir)
+		if err != nil {
+			return nil, err
+		}
+
+		processes = append(processes, packit.Process{
+			Type:    name,
+			Command: command,
+			Default: name == defaultProcess,
+		})
+	}
+
+	return processes, nil
+}
+
+func resolveScriptCommand(command, binDir string) (string, error) {
+	command = strings.TrimSpace(command)
+	if binDir == "" {
+		return command, nil
+	}
+
+	executable := command
+	rest := ""
+	if index := strings.IndexAny(command, " \t"); index >= 0 {
+		executable, rest = command[:index], command[index:]
+	}
+
+	if strings.ContainsRune(executable, filepath.Separator) {
+		return command, nil
+	}
+
+	exists, err := fs.Exists(filepath.Join(binDir, executable))
+	if err != nil {
+		return "", err
+	}
+
+	if !exists {
+		return command, nil
+	}
+
+	return filepath.Join(binDir, executable) + rest, nil
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/paketo-buildpacks/packit/v2/pexec"
 	"github.com/paketo-buildpacks/packit/v2/sbom"
 	"github.com/paketo-buildpacks/packit/v2/scribe"
+	"github.com/paketo-buildpacks/packit/v2/servicebindings"
 	pipenvinstall "github.com/paketo-buildpacks/pipenv-install"
 )
 
@@ -18,20 +19,40 @@ func (f Generator) Generate(dir string) (sbom.SBOM, error) {
 }
 
 func main() {
-	logger := scribe.NewEmitter(os.Stdout).WithLevel(os.Getenv("BP_LOG_LEVEL"))
+	writer := pipenvinstall.NewRedactingWriter(os.Stdout)
+	logger := scribe.NewEmitter(writer).WithLevel(os.Getenv("BP_LOG_LEVEL"))
+
+	detect := pipenvinstall.Detect(
+		pipenvinstall.NewPipfileParser(),
+		pipenvinstall.NewPipfileLockParser(),
+		pipenvinstall.NewPipfileParser(),
+		pipenvinstall.NewPipfileLockParser(),
+		logger,
+	)
+
+	build := pipenvinstall.Build(
+		pipenvinstall.NewPipenvInstallProcess(pexec.NewExecutable("pipenv"), logger),
+		pipenvinstall.NewSiteProcess(),
+		pipenvinstall.NewVenvLocator(),
+		Generator{},
+		pipenvinstall.NewPipfileLockParser(),
+		pipenvinstall.NewVersionProcess(pexec.NewExecutable("python")),
+		pipenvinstall.NewPipfileParser(),
+		pipenvinstall.NewPipenvScriptProcess(pexec.NewExecutable("pipenv"), logger),
+		servicebindings.NewResolver(),
+		chronos.DefaultClock,
+		logger,
+	)
 
 	packit.Run(
-		pipenvinstall.Detect(
-			pipenvinstall.NewPipfileParser(),
-			pipenvinstall.NewPipfileLockParser(),
-		),
-		pipenvinstall.Build(
-			pipenvinstall.NewPipenvInstallProcess(pexec.NewExecutable("pipenv"), logger),
-			pipenvinstall.NewSiteProcess(pexec.NewExecutable("python")),
-			pipenvinstall.NewVenvLocator(),
-			Generator{},
-			chronos.DefaultClock,
-			logger,
-		),
+		func(context packit.DetectContext) (packit.DetectResult, error) {
+			// packit exits on failure, write out the log before it does.
+			defer writer.Flush()
+			return detect(context)
+		},
+		func(context packit.BuildContext) (packit.BuildResult, error) {
+			defer writer.Flush()
+			return build(context)
+		},
 	)
 }
@@ -0,0 +1,53 @@
+package pipenvinstall
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/paketo-buildpacks/packit/v2"
+	"github.com/paketo-buildpacks/packit/v2/pexec"
+	"github.com/paketo-buildpacks/packit/v2/scribe"
+)
+
+// PipenvScriptProcess implements the PostInstallProcess interface.
+type PipenvScriptProcess struct {
+	executable Executable
+	logger     scribe.Emitter
+}
+
+// NewPipenvScriptProcess creates an instance of the PipenvScriptProcess given
+// an Executable that runs `pipenv`.
+func NewPipenvScriptProcess(executable Executable, logger scribe.Emitter) PipenvScriptProcess {
+	return PipenvScriptProcess{
+		executable: executable,
+		logger:     logger,
+	}
+}
+
+// Execute runs the workingDir/Pipfile [scripts] entry named script with
+// `pipenv run`, using the virtual environment at venvDir in the targetLayer.
+// The script output is streamed to the logger. Unlike the install, the script
+// runs with the whole build environment, as it runs the app code that reads
+// its configuration from there.
+func (p PipenvScriptProcess) Execute(workingDir, script string, targetLayer packit.Layer, venvDir string) error {
+	p.logger.Subprocess("Running 'pipenv run %s'", script)
+
+	err := p.executable.Execute(pexec.Execution{
+		Args: []string{"run", script},
+		Env: append(os.Environ(),
+			fmt.Sprintf("WORKON_HOME=%s", targetLayer.Path),
+			fmt.Sprintf("PIPENV_CUSTOM_VENV_NAME=%s", filepath.Base(venvDir)),
+			fmt.Sprintf("VIRTUAL_ENV=%s", venvDir),
+			fmt.Sprintf("PATH=%s", strings.Join([]string{filepath.Join(venvDir, "bin"), os.Getenv("PATH")}, string(os.PathListSeparator)))),
+		Dir:    workingDir,
+		Stdout: p.logger.ActionWriter,
+		Stderr: p.logger.ActionWriter,
+	})
+	if err != nil {
+		return fmt.Errorf("pipenv run %s failed: %w", script, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,94 @@
+package pipenvinstall_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/paketo-buildpacks/packit/v2"
+	"github.com/paketo-buildpacks/packit/v2/pexec"
+	"github.com/paketo-buildpacks/packit/v2/scribe"
+	pipenvinstall "github.com/paketo-buildpacks/pipenv-install"
+	"github.com/paketo-buildpacks/pipenv-install/fakes"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testScriptProcess(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		layerPath  string
+		venvDir    string
+		workingDir string
+		executable *fakes.Executable
+		buffer     *bytes.Buffer
+
+		process pipenvinstall.PipenvScriptProcess
+	)
+
+	it.Before(func() {
+		var err error
+		layerPath, err = os.MkdirTemp("", "layer")
+		Expect(err).NotTo(HaveOccurred())
+
+		workingDir, err = os.MkdirTemp("", "working-dir")
+		Expect(err).NotTo(HaveOccurred())
+
+		venvDir = filepath.Join(layerPath, "some-venv")
+
+		executable = &fakes.Executable{}
+		executable.ExecuteCall.Stub = func(execution pexec.Execution) error {
+			fmt.Fprintln(execution.Stdout, "stdout output")
+			fmt.Fprintln(execution.Stderr, "stderr output")
+			return nil
+		}
+
+		buffer = bytes.NewBuffer(nil)
+		process = pipenvinstall.NewPipenvScriptProcess(executable, scribe.NewEmitter(buffer))
+	})
+
+	it.After(func() {
+		Expect(os.RemoveAll(layerPath)).To(Succeed())
+		Expect(os.RemoveAll(workingDir)).To(Succeed())
+	})
+
+	context("Execute", func() {
+		it("runs the script in the venv and streams its output", func() {
+			err := process.Execute(workingDir, "collectstatic", packit.Layer{Path: layerPath}, venvDir)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(executable.ExecuteCall.Receives.Execution.Args).To(Equal([]string{"run", "collectstatic"}))
+			Expect(executable.ExecuteCall.Receives.Execution.Dir).To(Equal(workingDir))
+			Expect(executable.ExecuteCall.Receives.Execution.Env).To(ContainElements(
+				fmt.Sprintf("WORKON_HOME=%s", layerPath),
+				"PIPENV_CUSTOM_VENV_NAME=some-venv",
+				fmt.Sprintf("VIRTUAL_ENV=%s", venvDir),
+				fmt.Sprintf("PATH=%s", filepath.Join(venvDir, "bin")+string(os.PathListSeparator)+os.Getenv("PATH")),
+			))
+
+			Expect(buffer.String()).To(ContainSubstring("    Running 'pipenv run collectstatic'"))
+			Expect(buffer.String()).To(ContainSubstring("      stdout output"))
+			Expect(buffer.String()).To(ContainSubstring("      stderr output"))
+		})
+
+		context("failure cases", func() {
+			context("when the script exits with an error", func() {
+				it.Before(func() {
+					executable.ExecuteCall.Stub = func(execution pexec.Execution) error {
+						return errors.New("exit status 1")
+					}
+				})
+
+				it("returns an error", func() {
+					err := process.Execute(workingDir, "collectstatic", packit.Layer{Path: layerPath}, venvDir)
+					Expect(err).To(MatchError("pipenv run collectstatic failed: exit status 1"))
+				})
+			})
+		})
+	})
+}
@@ -1,45 +1,92 @@
 package pipenvinstall
 
 import (
-	"bytes"
+	"bufio"
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
-
-	"github.com/paketo-buildpacks/packit/v2/pexec"
 )
 
-// SiteProcess implements the Executable interface.
-type SiteProcess struct {
-	executable Executable
+// SiteProcess implements the SitePackagesProcess interface.
+type SiteProcess struct{}
+
+// NewSiteProcess creates an instance of the SiteProcess.
+func NewSiteProcess() SiteProcess {
+	return SiteProcess{}
 }
 
-// NewSiteProcess creates an instance of the SiteProcess given an Executable that runs `python`
-func NewSiteProcess(executable Executable) SiteProcess {
-	return SiteProcess{
-		executable: executable,
+// Execute locates the site-packages directory of the virtual env at venvDir,
+// where pipenv installed the packages. It is derived from the interpreter
+// version recorded in the venv pyvenv.cfg, i.e. lib/pythonX.Y/site-packages,
+// falling back to the only lib/*/site-packages directory of the venv for
+// interpreters with a different layout.
+func (p SiteProcess) Execute(venvDir string) (string, error) {
+	version, err := parsePyvenvVersion(filepath.Join(venvDir, "pyvenv.cfg"))
+	if err != nil {
+		return "", fmt.Errorf("failed to locate site packages: %w", err)
+	}
+
+	if version != "" {
+		path := filepath.Join(venvDir, "lib", fmt.Sprintf("python%s", minorVersion(version)), "site-packages")
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+
+	matches, err := filepath.Glob(filepath.Join(venvDir, "lib", "*", "site-packages"))
+	if err != nil {
+		return "", fmt.Errorf("failed to locate site packages: %w", err)
+	}
+
+	if len(matches) != 1 {
+		return "", fmt.Errorf("failed to locate site packages: found %d site-packages directories in %s", len(matches), venvDir)
 	}
+
+	return matches[0], nil
 }
 
-// Execute runs a python command to locate the site packages within the pip targetLayerPath.
-func (p SiteProcess) Execute(layerPath string) (string, error) {
-	buffer := bytes.NewBuffer(nil)
+// parsePyvenvVersion returns the interpreter version recorded in a pyvenv.cfg,
+// either by virtualenv (version_info = 3.11.7.final.0) or by the venv module
+// (version = 3.11.7).
+func parsePyvenvVersion(path string) (string, error) {
+	values, err := parsePyvenvConfig(path)
+	if err != nil {
+		return "", err
+	}
+
+	if version, ok := values["version_info"]; ok {
+		return version, nil
+	}
 
-	err := p.executable.Execute(pexec.Execution{
-		Args:   []string{"-m", "site", "--user-site"},
-		Env:    append(os.Environ(), fmt.Sprintf("PYTHONUSERBASE=%s", layerPath)),
-		Stdout: buffer,
-		Stderr: buffer,
-	})
+	return values["version"], nil
+}
+
+// parsePyvenvConfig returns the key = value pairs of a pyvenv.cfg.
+func parsePyvenvConfig(path string) (map[string]string, error) {
+	file, err := os.Open(path)
 	if err != nil {
-		return "", fmt.Errorf("failed to locate site packages:\n%s\nerror: %w", buffer.String(), err)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("%s does not exist", path)
+		}
+		return nil, err
 	}
+	defer file.Close()
 
-	path := strings.TrimSpace(buffer.String())
+	values := map[string]string{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		key, value, found := strings.Cut(scanner.Text(), "=")
+		if found {
+			values[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
 
-	if len(path) == 0 {
-		return "", fmt.Errorf("failed to locate site packages: output is empty")
+	err = scanner.Err()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
 	}
 
-	return path, nil
+	return values, nil
 }
@@ -1,15 +1,11 @@
 package pipenvinstall_test
 
 import (
-	"errors"
-	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
 
-	"github.com/paketo-buildpacks/packit/v2/pexec"
 	pipenvinstall "github.com/paketo-buildpacks/pipenv-install"
-	"github.com/paketo-buildpacks/pipenv-install/fakes"
 	"github.com/sclevine/spec"
 
 	. "github.com/onsi/gomega"
@@ -19,71 +15,103 @@ func testSiteProcess(t *testing.T, context spec.G, it spec.S) {
 	var (
 		Expect = NewWithT(t).Expect
 
-		layerPath  string
-		executable *fakes.Executable
+		venvDir string
 
 		process pipenvinstall.SiteProcess
 	)
 
 	it.Before(func() {
 		var err error
-		layerPath, err = os.MkdirTemp("", "layer")
+		venvDir, err = os.MkdirTemp("", "venv")
 		Expect(err).NotTo(HaveOccurred())
 
-		executable = &fakes.Executable{}
-		executable.ExecuteCall.Stub = func(execution pexec.Execution) error {
-			if execution.Stdout != nil {
-				fmt.Fprintln(execution.Stdout, filepath.Join(layerPath, "/pip/lib/python/site-packages"))
-			}
-			return nil
-		}
+		Expect(os.MkdirAll(filepath.Join(venvDir, "lib", "python3.11", "site-packages"), os.ModePerm)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(venvDir, "pyvenv.cfg"), []byte(`home = /layers/cpython/bin
+implementation = CPython
+version_info = 3.11.7.final.0
+virtualenv = 20.25.0
+include-system-site-packages = false
+`), os.ModePerm)).To(Succeed())
 
-		process = pipenvinstall.NewSiteProcess(executable)
+		process = pipenvinstall.NewSiteProcess()
 	})
 
 	it.After(func() {
-		Expect(os.RemoveAll(layerPath)).To(Succeed())
+		Expect(os.RemoveAll(venvDir)).To(Succeed())
 	})
 
 	context("Execute", func() {
-		context("there are site packages in the pipenv layer", func() {
-			it("returns the full path to the packages", func() {
-				sitePackagesPath, err := process.Execute(layerPath)
+		context("when the venv was created by virtualenv", func() {
+			it("returns the full path to the venv site packages", func() {
+				sitePackagesPath, err := process.Execute(venvDir)
 				Expect(err).NotTo(HaveOccurred())
 
-				Expect(executable.ExecuteCall.Receives.Execution.Env).To(Equal(append(os.Environ(), fmt.Sprintf("PYTHONUSERBASE=%s", layerPath))))
-				Expect(executable.ExecuteCall.Receives.Execution.Args).To(Equal([]string{"-m", "site", "--user-site"}))
+				Expect(sitePackagesPath).To(Equal(filepath.Join(venvDir, "lib", "python3.11", "site-packages")))
+			})
+		})
+
+		context("when the venv was created by the venv module", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(venvDir, "pyvenv.cfg"), []byte(`home = /layers/cpython/bin
+include-system-site-packages = false
+version = 3.11.7
+`), os.ModePerm)).To(Succeed())
+			})
+
+			it("returns the full path to the venv site packages", func() {
+				sitePackagesPath, err := process.Execute(venvDir)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(sitePackagesPath).To(Equal(filepath.Join(venvDir, "lib", "python3.11", "site-packages")))
+			})
+		})
+
+		context("when the lib layout does not match the version", func() {
+			it.Before(func() {
+				Expect(os.RemoveAll(filepath.Join(venvDir, "lib"))).To(Succeed())
+				Expect(os.MkdirAll(filepath.Join(venvDir, "lib", "pypy3.10", "site-packages"), os.ModePerm)).To(Succeed())
+			})
+
+			it("returns the only site packages directory of the venv", func() {
+				sitePackagesPath, err := process.Execute(venvDir)
+				Expect(err).NotTo(HaveOccurred())
 
-				Expect(sitePackagesPath).To(Equal(filepath.Join(layerPath, "pip", "lib", "python", "site-packages")))
+				Expect(sitePackagesPath).To(Equal(filepath.Join(venvDir, "lib", "pypy3.10", "site-packages")))
 			})
 		})
 
 		context("failure cases", func() {
-			context("site package lookup fails", func() {
+			context("when the pyvenv.cfg does not exist", func() {
 				it.Before(func() {
-					executable.ExecuteCall.Stub = func(execution pexec.Execution) error {
-						fmt.Fprintln(execution.Stdout, "stdout output")
-						fmt.Fprintln(execution.Stderr, "stderr output")
-						return errors.New("locating site packages failed")
-					}
+					Expect(os.Remove(filepath.Join(venvDir, "pyvenv.cfg"))).To(Succeed())
 				})
 
 				it("returns an error", func() {
-					_, err := process.Execute(layerPath)
+					_, err := process.Execute(venvDir)
 					Expect(err).To(MatchError(ContainSubstring("failed to locate site packages:")))
-					Expect(err).To(MatchError(ContainSubstring("stderr output")))
-					Expect(err).To(MatchError(ContainSubstring("error: locating site packages failed")))
+					Expect(err).To(MatchError(ContainSubstring("pyvenv.cfg does not exist")))
+				})
+			})
+
+			context("when the pyvenv.cfg cannot be read", func() {
+				it.Before(func() {
+					Expect(os.Chmod(filepath.Join(venvDir, "pyvenv.cfg"), 0000)).To(Succeed())
+				})
+
+				it("returns an error", func() {
+					_, err := process.Execute(venvDir)
+					Expect(err).To(MatchError(ContainSubstring("permission denied")))
 				})
 			})
 
-			context("when the site process returns nothing", func() {
+			context("when the venv has no site packages directory", func() {
 				it.Before(func() {
-					executable.ExecuteCall.Stub = nil
+					Expect(os.RemoveAll(filepath.Join(venvDir, "lib"))).To(Succeed())
 				})
 
 				it("returns an error", func() {
-					_, err := process.Execute(layerPath)
-					Expect(err).To(MatchError("failed to locate site packages: output is empty"))
+					_, err := process.Execute(venvDir)
+					Expect(err).To(MatchError(ContainSubstring("found 0 site-packages directories")))
 				})
 			})
 		})
@@ -0,0 +1,146 @@
+package pipenvinstall
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// CheckVenvInterpreter checks the bin/python interpreter link and the
+// pyvenv.cfg home of the virtual env at venvDir, which point at the python
+// the venv was created with. When the link is broken, e.g. because the
+// cpython layer moved, it is pointed at the pythonX.Y of the same minor
+// version found on pathList outside of the venv. The pyvenv.cfg home is
+// updated to match. It returns the interpreter the link points to, and
+// whether it was repaired.
+func CheckVenvInterpreter(venvDir, pathList string) (target string, repaired bool, err error) {
+	binDir := filepath.Join(venvDir, "bin")
+	link := filepath.Join(binDir, "python")
+	configPath := filepath.Join(venvDir, "pyvenv.cfg")
+
+	info, err := os.Lstat(link)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to check virtual env interpreter: %w", err)
+	}
+
+	// A venv created with --copies holds its own interpreter.
+	if info.Mode()&os.ModeSymlink == 0 {
+		return link, false, nil
+	}
+
+	target, err = os.Readlink(link)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to read virtual env interpreter link: %w", err)
+	}
+
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(binDir, target)
+	}
+
+	if _, err := os.Stat(link); err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			return "", false, fmt.Errorf("failed to check virtual env interpreter: %w", err)
+		}
+
+		version, err := parsePyvenvVersion(configPath)
+		if err != nil {
+			return "", false, fmt.Errorf("failed to check virtual env interpreter: %w", err)
+		}
+
+		name := fmt.Sprintf("python%s", minorVersion(version))
+		replacement := findExecutable(name, pathList, binDir)
+		if version == "" || replacement == "" {
+			return "", false, fmt.Errorf("virtual env interpreter %s links to %s which does not exist, and no %s was found on PATH", link, target, name)
+		}
+
+		err = os.Remove(link)
+		if err != nil {
+			return "", false, fmt.Errorf("failed to repair virtual env interpreter link: %w", err)
+		}
+
+		err = os.Symlink(replacement, link)
+		if err != nil {
+			return "", false, fmt.Errorf("failed to repair virtual env interpreter link: %w", err)
+		}
+
+		target = replacement
+		repaired = true
+	}
+
+	config, err := parsePyvenvConfig(configPath)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to check virtual env interpreter: %w", err)
+	}
+
+	// The home of a healthy venv may differ from the link target directory,
+	// e.g. when the link goes through a python3 -> python3.11 symlink.
+	home := config["home"]
+	if _, err := os.Stat(home); repaired || home == "" || err != nil {
+		err = rewritePyvenvHome(configPath, target)
+		if err != nil {
+			return "", false, err
+		}
+		repaired = true
+	}
+
+	return target, repaired, nil
+}
+
+// findExecutable returns the first name executable in the pathList
+// directories, skipping the excluded directory.
+func findExecutable(name, pathList, excluded string) string {
+	for _, dir := range filepath.SplitList(pathList) {
+		if dir == "" || filepath.Clean(dir) == filepath.Clean(excluded) {
+			continue
+		}
+
+		path := filepath.Join(dir, name)
+		info, err := os.Stat(path)
+		if err == nil && !info.IsDir() && info.Mode()&0111 != 0 {
+			return path
+		}
+	}
+
+	return ""
+}
+
+// rewritePyvenvHome points the home, and the base-prefix, base-exec-prefix
+// and base-executable written by virtualenv, of the pyvenv.cfg at path at the
+// python installation of the given interpreter.
+func rewritePyvenvHome(path, interpreter string) error {
+	home := filepath.Dir(interpreter)
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	values := map[string]string{
+		"home":             home,
+		"base-prefix":      filepath.Dir(home),
+		"base-exec-prefix": filepath.Dir(home),
+		"base-executable":  interpreter,
+	}
+
+	lines := strings.Split(string(content), "\n")
+	for i, line := range lines {
+		key, _, found := strings.Cut(line, "=")
+		if value, ok := values[strings.TrimSpace(key)]; found && ok {
+			lines[i] = fmt.Sprintf("%s = %s", strings.TrimSpace(key), value)
+		}
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	err = os.WriteFile(path, []byte(strings.Join(lines, "\n")), info.Mode())
+	if err != nil {
+		return fmt.Errorf("failed to update %s: %w", path, err)
+	}
+
+	return nil
+}
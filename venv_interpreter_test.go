@@ -0,0 +1,131 @@
+package pipenvinstall_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	pipenvinstall "github.com/paketo-buildpacks/pipenv-install"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testVenvInterpreter(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		venvDir   string
+		pythonDir string
+		otherDir  string
+	)
+
+	it.Before(func() {
+		var err error
+		pythonDir, err = os.MkdirTemp("", "cpython")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.MkdirAll(filepath.Join(pythonDir, "bin"), os.ModePerm)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(pythonDir, "bin", "python3.11"), nil, 0755)).To(Succeed())
+
+		otherDir, err = os.MkdirTemp("", "other-cpython")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.MkdirAll(filepath.Join(otherDir, "bin"), os.ModePerm)).To(Succeed())
+
+		venvDir, err = os.MkdirTemp("", "venv")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.MkdirAll(filepath.Join(venvDir, "bin"), os.ModePerm)).To(Succeed())
+		Expect(os.Symlink(filepath.Join(pythonDir, "bin", "python3.11"), filepath.Join(venvDir, "bin", "python"))).To(Succeed())
+		Expect(os.Symlink("python", filepath.Join(venvDir, "bin", "python3"))).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(venvDir, "pyvenv.cfg"), []byte(fmt.Sprintf(`home = %[1]s/bin
+implementation = CPython
+version_info = 3.11.7.final.0
+base-prefix = %[1]s
+base-exec-prefix = %[1]s
+base-executable = %[1]s/bin/python3.11
+`, pythonDir)), os.ModePerm)).To(Succeed())
+	})
+
+	it.After(func() {
+		Expect(os.RemoveAll(venvDir)).To(Succeed())
+		Expect(os.RemoveAll(pythonDir)).To(Succeed())
+		Expect(os.RemoveAll(otherDir)).To(Succeed())
+	})
+
+	context("CheckVenvInterpreter", func() {
+		it("returns the interpreter the venv links to", func() {
+			target, repaired, err := pipenvinstall.CheckVenvInterpreter(venvDir, filepath.Join(pythonDir, "bin"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(target).To(Equal(filepath.Join(pythonDir, "bin", "python3.11")))
+			Expect(repaired).To(BeFalse())
+		})
+
+		context("when the interpreter has moved", func() {
+			it.Before(func() {
+				Expect(os.Rename(filepath.Join(pythonDir, "bin", "python3.11"), filepath.Join(otherDir, "bin", "python3.11"))).To(Succeed())
+				Expect(os.RemoveAll(filepath.Join(pythonDir, "bin"))).To(Succeed())
+			})
+
+			it("points the venv at the python of the same minor version on the PATH", func() {
+				pathList := fmt.Sprintf("%s%c%s", filepath.Join(venvDir, "bin"), os.PathListSeparator, filepath.Join(otherDir, "bin"))
+				target, repaired, err := pipenvinstall.CheckVenvInterpreter(venvDir, pathList)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(target).To(Equal(filepath.Join(otherDir, "bin", "python3.11")))
+				Expect(repaired).To(BeTrue())
+
+				link, err := os.Readlink(filepath.Join(venvDir, "bin", "python"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(link).To(Equal(filepath.Join(otherDir, "bin", "python3.11")))
+				Expect(filepath.Join(venvDir, "bin", "python3")).To(BeARegularFile())
+
+				content, err := os.ReadFile(filepath.Join(venvDir, "pyvenv.cfg"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(content)).To(Equal(fmt.Sprintf(`home = %[1]s/bin
+implementation = CPython
+version_info = 3.11.7.final.0
+base-prefix = %[1]s
+base-exec-prefix = %[1]s
+base-executable = %[1]s/bin/python3.11
+`, otherDir)))
+			})
+
+			context("when no python of the same minor version is on the PATH", func() {
+				it.Before(func() {
+					Expect(os.Rename(filepath.Join(otherDir, "bin", "python3.11"), filepath.Join(otherDir, "bin", "python3.12"))).To(Succeed())
+				})
+
+				it("returns an error", func() {
+					_, _, err := pipenvinstall.CheckVenvInterpreter(venvDir, filepath.Join(otherDir, "bin"))
+					Expect(err).To(MatchError(ContainSubstring("which does not exist, and no python3.11 was found on PATH")))
+				})
+			})
+		})
+
+		context("when the venv holds a copy of the interpreter", func() {
+			it.Before(func() {
+				Expect(os.Remove(filepath.Join(venvDir, "bin", "python"))).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(venvDir, "bin", "python"), nil, 0755)).To(Succeed())
+			})
+
+			it("returns the copy", func() {
+				target, repaired, err := pipenvinstall.CheckVenvInterpreter(venvDir, "")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(target).To(Equal(filepath.Join(venvDir, "bin", "python")))
+				Expect(repaired).To(BeFalse())
+			})
+		})
+
+		context("failure cases", func() {
+			context("when the venv has no interpreter", func() {
+				it.Before(func() {
+					Expect(os.Remove(filepath.Join(venvDir, "bin", "python"))).To(Succeed())
+				})
+
+				it("returns an error", func() {
+					_, _, err := pipenvinstall.CheckVenvInterpreter(venvDir, "")
+					Expect(err).To(MatchError(ContainSubstring("failed to check virtual env interpreter")))
+				})
+			})
+		})
+	})
+}
@@ -1,11 +1,16 @@
 package pipenvinstall
 
 import (
+	"crypto/sha256"
+	"encoding/base64"
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 
 	"github.com/paketo-buildpacks/packit/v2"
+	"github.com/paketo-buildpacks/packit/v2/fs"
 )
 
 type VenvLocator struct {
@@ -15,36 +20,86 @@ func NewVenvLocator() VenvLocator {
 	return VenvLocator{}
 }
 
-// It would have been cleaner to run "pipenv --venv"
-// and extract out the exact virtual env dir,
-// but it doesn't seem to work.
-// So we look for the dir with pyvenv.cfg in $WORKON_HOME
+// LocateVenvDir returns the virtual env dir of the workingDir project in
+// $WORKON_HOME at path. The install process names it with
+// $PIPENV_CUSTOM_VENV_NAME, so there is exactly one candidate.
+func (v VenvLocator) LocateVenvDir(path, workingDir string) (string, error) {
+	name, err := venvName(workingDir)
+	if err != nil {
+		return "", err
+	}
+
+	venvDir := filepath.Join(path, name)
+	_, err = os.Stat(filepath.Join(venvDir, "pyvenv.cfg"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", packit.Fail.WithMessage("pipenv virtual env directory %s not found in target %s", name, path)
+		}
+		return "", packit.Fail.WithMessage("pipenv virtual env dir lookup failed in target %s: %w", path, err)
+	}
+
+	return venvDir, nil
+}
+
+// unsafeVenvNameCharacters are the characters pipenv replaces with "_" in the
+// project name part of a virtual env name.
+var unsafeVenvNameCharacters = regexp.MustCompile("[ &$`!*@\"()\\[\\]\\\\\r\n\t]")
+
+// venvName returns the name of the virtual env of the project in workingDir:
+// $PIPENV_CUSTOM_VENV_NAME when it is set, and otherwise the name pipenv
+// derives from the project directory name and a hash of the Pipfile location,
+// e.g. "MY_PROJECT-wyUfYPqE" for /home/user/MY_PROJECT.
+func venvName(workingDir string) (string, error) {
+	if name := os.Getenv("PIPENV_CUSTOM_VENV_NAME"); name != "" {
+		return name, nil
+	}
+
+	projectDir, err := filepath.Abs(workingDir)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve project directory: %w", err)
+	}
+
+	// pipenv hashes the resolved Pipfile location.
+	if resolved, err := filepath.EvalSymlinks(projectDir); err == nil {
+		projectDir = resolved
+	}
 
-func (v VenvLocator) LocateVenvDir(path string) (string, error) {
+	name := unsafeVenvNameCharacters.ReplaceAllString(filepath.Base(projectDir), "_")
+	if len(name) > 42 {
+		name = name[:42]
+	}
+
+	sum := sha256.Sum256([]byte(filepath.Join(projectDir, "Pipfile")))
+	hash := base64.URLEncoding.EncodeToString(sum[:6])
+
+	return fmt.Sprintf("%s-%s", name, hash[:8]), nil
+}
+
+// removeStaleVenvs removes every virtual env dir in $WORKON_HOME at path other
+// than the one named name, e.g. those left behind by a previous project name.
+func removeStaleVenvs(path, name string) error {
 	entries, err := os.ReadDir(path)
 	if err != nil {
-		return "", packit.Fail.WithMessage("reading target directory %s failed:\nerror: %w", path, err)
+		return fmt.Errorf("failed to read %s: %w", path, err)
 	}
 
-	venvDir := ""
 	for _, entry := range entries {
-		if !entry.IsDir() {
+		if !entry.IsDir() || entry.Name() == name {
 			continue
 		}
-		_, err := os.Stat(filepath.Join(path, entry.Name(), "pyvenv.cfg"))
+
+		exists, err := fs.Exists(filepath.Join(path, entry.Name(), "pyvenv.cfg"))
 		if err != nil {
-			if errors.Is(err, os.ErrNotExist) {
-				continue
-			}
-			return "", packit.Fail.WithMessage("pipenv virtual env dir lookup failed in target %s: %w", path, err)
+			return err
 		}
-		venvDir = entry.Name()
-		break
-	}
 
-	if venvDir == "" {
-		return "", packit.Fail.WithMessage("pipenv virtual env directory not found in target %s", path)
+		if exists {
+			err = os.RemoveAll(filepath.Join(path, entry.Name()))
+			if err != nil {
+				return fmt.Errorf("failed to remove stale virtual env %s: %w", entry.Name(), err)
+			}
+		}
 	}
 
-	return filepath.Join(path, venvDir), nil
+	return nil
 }
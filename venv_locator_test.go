@@ -1,6 +1,9 @@
 package pipenvinstall_test
 
 import (
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
@@ -15,7 +18,9 @@ func testVenvLocator(t *testing.T, context spec.G, it spec.S) {
 	var (
 		Expect = NewWithT(t).Expect
 
-		layerPath string
+		layerPath  string
+		workingDir string
+		venvName   string
 
 		process pipenvinstall.VenvLocator
 	)
@@ -25,8 +30,25 @@ func testVenvLocator(t *testing.T, context spec.G, it spec.S) {
 		layerPath, err = os.MkdirTemp("", "layer")
 		Expect(err).NotTo(HaveOccurred())
 
-		Expect(os.Mkdir(filepath.Join(layerPath, "some-virtualenv-dir"), os.ModePerm)).To(Succeed())
-		Expect(os.WriteFile(filepath.Join(layerPath, "some-virtualenv-dir", "pyvenv.cfg"), nil, os.ModePerm)).To(Succeed())
+		parentDir, err := os.MkdirTemp("", "working-dir")
+		Expect(err).NotTo(HaveOccurred())
+
+		workingDir = filepath.Join(parentDir, "my (project)")
+		Expect(os.Mkdir(workingDir, os.ModePerm)).To(Succeed())
+
+		workingDir, err = filepath.EvalSymlinks(workingDir)
+		Expect(err).NotTo(HaveOccurred())
+
+		// pipenv names the virtual env after the project directory and the
+		// hash of the Pipfile location.
+		sum := sha256.Sum256([]byte(filepath.Join(workingDir, "Pipfile")))
+		venvName = fmt.Sprintf("my__project_-%s", base64.URLEncoding.EncodeToString(sum[:6])[:8])
+
+		Expect(os.Mkdir(filepath.Join(layerPath, venvName), os.ModePerm)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(layerPath, venvName, "pyvenv.cfg"), nil, os.ModePerm)).To(Succeed())
+
+		Expect(os.Mkdir(filepath.Join(layerPath, "some-stale-virtualenv-dir"), os.ModePerm)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(layerPath, "some-stale-virtualenv-dir", "pyvenv.cfg"), nil, os.ModePerm)).To(Succeed())
 
 		Expect(os.Mkdir(filepath.Join(layerPath, "some-other-dir"), os.ModePerm)).To(Succeed())
 
@@ -35,43 +57,46 @@ func testVenvLocator(t *testing.T, context spec.G, it spec.S) {
 
 	it.After(func() {
 		Expect(os.RemoveAll(layerPath)).To(Succeed())
+		Expect(os.RemoveAll(filepath.Dir(workingDir))).To(Succeed())
 	})
 
 	context("LocateVenvDir", func() {
-		it("returns the full path to the packages", func() {
-			venvDir, err := process.LocateVenvDir(layerPath)
+		it("returns the full path to the project virtual env", func() {
+			venvDir, err := process.LocateVenvDir(layerPath, workingDir)
 			Expect(err).NotTo(HaveOccurred())
 
-			Expect(venvDir).To(Equal(filepath.Join(layerPath, "some-virtualenv-dir")))
+			Expect(venvDir).To(Equal(filepath.Join(layerPath, venvName)))
 		})
 
-		context("failure cases", func() {
-			context("when reading the root directory fails", func() {
-				it.Before(func() {
-					Expect(os.Chmod(layerPath, 0000)).To(Succeed())
-				})
+		context("when PIPENV_CUSTOM_VENV_NAME is set", func() {
+			it.Before(func() {
+				Expect(os.Setenv("PIPENV_CUSTOM_VENV_NAME", "some-stale-virtualenv-dir")).To(Succeed())
+			})
 
-				it.After(func() {
-					Expect(os.Chmod(layerPath, os.ModePerm)).To(Succeed())
-				})
+			it.After(func() {
+				Expect(os.Unsetenv("PIPENV_CUSTOM_VENV_NAME")).To(Succeed())
+			})
 
-				it("returns an error", func() {
-					_, err := process.LocateVenvDir(layerPath)
-					Expect(err).To(MatchError(ContainSubstring("permission denied")))
-				})
+			it("returns the virtual env with that name", func() {
+				venvDir, err := process.LocateVenvDir(layerPath, workingDir)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(venvDir).To(Equal(filepath.Join(layerPath, "some-stale-virtualenv-dir")))
 			})
+		})
 
-			context("when reading a subdirectory fails", func() {
+		context("failure cases", func() {
+			context("when reading the virtual env directory fails", func() {
 				it.Before(func() {
-					Expect(os.Chmod(filepath.Join(layerPath, "some-virtualenv-dir"), 0000)).To(Succeed())
+					Expect(os.Chmod(filepath.Join(layerPath, venvName), 0000)).To(Succeed())
 				})
 
 				it.After(func() {
-					Expect(os.Chmod(filepath.Join(layerPath, "some-virtualenv-dir"), os.ModePerm)).To(Succeed())
+					Expect(os.Chmod(filepath.Join(layerPath, venvName), os.ModePerm)).To(Succeed())
 				})
 
 				it("returns an error", func() {
-					_, err := process.LocateVenvDir(layerPath)
+					_, err := process.LocateVenvDir(layerPath, workingDir)
 					Expect(err).To(MatchError(ContainSubstring("lookup failed")))
 				})
 			})
@@ -89,8 +114,8 @@ func testVenvLocator(t *testing.T, context spec.G, it spec.S) {
 				})
 
 				it("returns an error", func() {
-					_, err := process.LocateVenvDir(emptyLayerPath)
-					Expect(err).To(MatchError(ContainSubstring("virtual env directory not found")))
+					_, err := process.LocateVenvDir(emptyLayerPath, workingDir)
+					Expect(err).To(MatchError(ContainSubstring(fmt.Sprintf("virtual env directory %s not found", venvName))))
 				})
 			})
 		})
@@ -0,0 +1,43 @@
+package pipenvinstall
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/paketo-buildpacks/packit/v2/pexec"
+)
+
+// VersionProcess implements the PythonVersionProcess interface.
+type VersionProcess struct {
+	executable Executable
+}
+
+// NewVersionProcess creates an instance of the VersionProcess given an Executable that runs `python`
+func NewVersionProcess(executable Executable) VersionProcess {
+	return VersionProcess{
+		executable: executable,
+	}
+}
+
+// Execute runs `python --version` and returns the version of the interpreter
+// available on the PATH, e.g. "3.11.7".
+func (p VersionProcess) Execute() (string, error) {
+	buffer := bytes.NewBuffer(nil)
+
+	err := p.executable.Execute(pexec.Execution{
+		Args:   []string{"--version"},
+		Stdout: buffer,
+		Stderr: buffer,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to determine python version:\n%s\nerror: %w", buffer.String(), err)
+	}
+
+	fields := strings.Fields(buffer.String())
+	if len(fields) != 2 || fields[0] != "Python" {
+		return "", fmt.Errorf("failed to determine python version: unexpected output %q", strings.TrimSpace(buffer.String()))
+	}
+
+	return fields[1], nil
+}
@@ -0,0 +1,73 @@
+package pipenvinstall_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/paketo-buildpacks/packit/v2/pexec"
+	pipenvinstall "github.com/paketo-buildpacks/pipenv-install"
+	"github.com/paketo-buildpacks/pipenv-install/fakes"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testVersionProcess(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		executable *fakes.Executable
+
+		process pipenvinstall.VersionProcess
+	)
+
+	it.Before(func() {
+		executable = &fakes.Executable{}
+		executable.ExecuteCall.Stub = func(execution pexec.Execution) error {
+			fmt.Fprintln(execution.Stdout, "Python 3.11.7")
+			return nil
+		}
+
+		process = pipenvinstall.NewVersionProcess(executable)
+	})
+
+	context("Execute", func() {
+		it("returns the python version", func() {
+			version, err := process.Execute()
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(executable.ExecuteCall.Receives.Execution.Args).To(Equal([]string{"--version"}))
+			Expect(version).To(Equal("3.11.7"))
+		})
+
+		context("failure cases", func() {
+			context("when python fails", func() {
+				it.Before(func() {
+					executable.ExecuteCall.Stub = func(execution pexec.Execution) error {
+						fmt.Fprintln(execution.Stderr, "stderr output")
+						return errors.New("python failed")
+					}
+				})
+
+				it("returns an error", func() {
+					_, err := process.Execute()
+					Expect(err).To(MatchError(ContainSubstring("failed to determine python version:")))
+					Expect(err).To(MatchError(ContainSubstring("stderr output")))
+					Expect(err).To(MatchError(ContainSubstring("error: python failed")))
+				})
+			})
+
+			context("when the output is not a python version", func() {
+				it.Before(func() {
+					executable.ExecuteCall.Stub = nil
+				})
+
+				it("returns an error", func() {
+					_, err := process.Execute()
+					Expect(err).To(MatchError(`failed to determine python version: unexpected output ""`))
+				})
+			})
+		})
+	})
+}